@@ -51,6 +51,7 @@ type App struct {
 	voters          int
 	standbys        int
 	roles           RolesConfig
+	keepMaintenance bool // Honor maintenance mode across leader changes
 }
 
 // New creates a new application node.
@@ -186,8 +187,7 @@ func New(dir string, options ...Option) (app *App, err error) {
 		nodeBindAddress = info.Address
 		nodeDial = client.DefaultDialFunc
 	}
-	node, err := dqlite.New(
-		info.ID, info.Address, dir,
+	nodeOptions := []dqlite.Option{
 		dqlite.WithBindAddress(nodeBindAddress),
 		dqlite.WithDialFunc(nodeDial),
 		dqlite.WithFailureDomain(o.FailureDomain),
@@ -195,7 +195,12 @@ func New(dir string, options ...Option) (app *App, err error) {
 		dqlite.WithSnapshotParams(o.SnapshotParams),
 		dqlite.WithDiskMode(o.DiskMode),
 		dqlite.WithAutoRecovery(o.AutoRecovery),
-	)
+	}
+	if o.Conn != nil {
+		// External connections may use any address format.
+		nodeOptions = append(nodeOptions, dqlite.WithNodeSkipAddressValidation())
+	}
+	node, err := dqlite.New(info.ID, info.Address, dir, nodeOptions...)
 	if err != nil {
 		stop()
 		return nil, fmt.Errorf("create node: %w", err)
@@ -256,6 +261,7 @@ func New(dir string, options ...Option) (app *App, err error) {
 		voters:          o.Voters,
 		standbys:        o.StandBys,
 		roles:           RolesConfig{Voters: o.Voters, StandBys: o.StandBys},
+		keepMaintenance: o.PersistentMaintenance,
 	}
 
 	// Start the proxy if a TLS configuration was provided.
@@ -600,8 +606,32 @@ func (a *App) run(ctx context.Context, frequency time.Duration, join bool) {
 	}
 }
 
+// Return true if automatic role changes are currently paused by the
+// cluster-wide maintenance flag. Unless WithPersistentMaintenance was used,
+// the flag is honored only if it was set while the given node was leader.
+//
+// If the flag can't be read, it's considered off, so a transient failure
+// doesn't stop role management.
+func (a *App) inMaintenance(ctx context.Context, cli *client.Client, leader uint64) bool {
+	info, err := cli.Maintenance(ctx)
+	if err != nil {
+		a.warn("check maintenance mode: %v", err)
+		return false
+	}
+	return info.Enabled && (a.keepMaintenance || info.LeaderID == leader)
+}
+
 // Possibly change our own role at startup.
 func (a *App) maybePromoteOurselves(ctx context.Context, cli *client.Client, nodes []client.NodeInfo) error {
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return err
+	}
+	if a.inMaintenance(ctx, cli, leader.ID) {
+		a.debug("maintenance mode on: skip promoting ourselves")
+		return nil
+	}
+
 	roles := a.makeRolesChanges(nodes)
 
 	role := roles.Assume(a.id)
@@ -645,6 +675,10 @@ again:
 		return nil
 	}
 
+	if a.inMaintenance(ctx, cli, info.ID) {
+		return nil
+	}
+
 	nodes, err := cli.Cluster(ctx)
 	if err != nil {
 		return err
@@ -578,6 +578,87 @@ func TestRolesAdjustment_ReplaceVoter(t *testing.T) {
 	assert.Equal(t, client.Voter, cluster[3].Role)
 }
 
+// While in maintenance mode, a voter going offline is not replaced until the
+// mode is turned off.
+func TestRolesAdjustment_Maintenance(t *testing.T) {
+	n := 4
+	apps := make([]*app.App, n)
+	cleanups := make([]func(), n)
+
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.1:900%d", i+1)
+		options := []app.Option{
+			app.WithAddress(addr),
+			app.WithRolesAdjustmentFrequency(time.Second),
+		}
+		if i > 0 {
+			options = append(options, app.WithCluster([]string{"127.0.0.1:9001"}))
+		}
+
+		app, cleanup := newApp(t, options...)
+
+		require.NoError(t, app.Ready(context.Background()))
+
+		apps[i] = app
+		cleanups[i] = cleanup
+	}
+
+	defer cleanups[0]()
+	defer cleanups[1]()
+	defer cleanups[3]()
+
+	cli, err := apps[0].Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	require.NoError(t, cli.SetMaintenance(context.Background(), true))
+
+	// A voter goes offline.
+	cleanups[2]()
+
+	roles := func() (client.NodeRole, client.NodeRole) {
+		cluster, err := cli.Cluster(context.Background())
+		require.NoError(t, err)
+		return cluster[2].Role, cluster[3].Role
+	}
+
+	// No adjustment happens for a few rounds.
+	assert.Never(t, func() bool {
+		offline, spare := roles()
+		return offline != client.Voter || spare != client.StandBy
+	}, 4*time.Second, 250*time.Millisecond)
+
+	require.NoError(t, cli.SetMaintenance(context.Background(), false))
+
+	assert.Eventually(t, func() bool {
+		offline, spare := roles()
+		return offline == client.Spare && spare == client.Voter
+	}, 10*time.Second, 250*time.Millisecond)
+}
+
+// Checking the maintenance flag on every roles round doesn't write anything,
+// so a cluster that never sets the flag has no system database.
+func TestRolesAdjustment_NoSystemDatabase(t *testing.T) {
+	app, cleanup := newApp(t, app.WithAddress("127.0.0.1:9001"), app.WithRolesAdjustmentFrequency(100*time.Millisecond))
+	defer cleanup()
+
+	require.NoError(t, app.Ready(context.Background()))
+
+	// Let a few roles rounds run.
+	time.Sleep(time.Second)
+
+	cli, err := app.Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	files, err := cli.Dump(context.Background(), client.SystemDatabase)
+	if err == nil {
+		for _, file := range files {
+			assert.Empty(t, file.Data, file.Name)
+		}
+	}
+}
+
 // If a voter goes offline, another node takes its place. If possible, pick a
 // voter from a failure domain which differs from the one of the two other
 // voters.
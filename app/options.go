@@ -203,6 +203,17 @@ func WithAutoRecovery(recovery bool) Option {
 	}
 }
 
+// WithPersistentMaintenance sets whether the cluster-wide maintenance flag,
+// set with client.Client.SetMaintenance, keeps pausing automatic role changes
+// after the leader that was in charge when it was set loses leadership.
+//
+// By default a leader change implicitly ends maintenance mode.
+func WithPersistentMaintenance(persist bool) Option {
+	return func(options *options) {
+		options.PersistentMaintenance = persist
+	}
+}
+
 type tlsSetup struct {
 	Listen *tls.Config
 	Dial   *tls.Config
@@ -229,6 +240,7 @@ type options struct {
 	SnapshotParams           dqlite.SnapshotParams
 	DiskMode                 bool
 	AutoRecovery             bool
+	PersistentMaintenance    bool
 }
 
 // Create a options object with sane defaults.
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// How long a node ID returned by AllocateNodeID stays reserved.
+const nodeIDReservationTTL = 10 * time.Minute
+
+// How many random candidates AllocateNodeID tries before giving up.
+const nodeIDAttempts = 16
+
+// AllocateNodeID returns a new random node ID that is not used by any node in
+// the current cluster configuration and is not reserved by a previous call.
+//
+// The ID is reserved in a replicated table of the SystemDatabase, so
+// concurrent callers never get the same ID, even when using different
+// clients. The reservation expires after 10 minutes: the caller should add
+// the node with this ID before then. Expiry is based on the clocks of the
+// calling hosts, which are expected to be roughly in sync.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) AllocateNodeID(ctx context.Context) (uint64, error) {
+	nodes, err := c.Cluster(ctx)
+	if err != nil {
+		return 0, err
+	}
+	used := make(map[uint64]bool, len(nodes))
+	for _, node := range nodes {
+		used[node.ID] = true
+	}
+
+	cli, db, err := c.openSystem(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+	defer cli.Close()
+
+	now := time.Now()
+
+	sql := "DELETE FROM node_id_reservations WHERE expires < ?"
+	values := []driver.NamedValue{{Ordinal: 1, Value: now.UnixNano()}}
+	if err := cli.exec(ctx, db, sql, values); err != nil {
+		return 0, errors.Wrap(err, "failed to delete expired reservations")
+	}
+
+	sql = "INSERT OR IGNORE INTO node_id_reservations (id, expires) VALUES (?, ?)"
+	for i := 0; i < nodeIDAttempts; i++ {
+		id, err := randomNodeID()
+		if err != nil {
+			return 0, err
+		}
+		if used[id] {
+			continue
+		}
+
+		// IDs are stored as signed integers, which preserves their
+		// uniqueness.
+		values := []driver.NamedValue{
+			{Ordinal: 1, Value: int64(id)},
+			{Ordinal: 2, Value: now.Add(nodeIDReservationTTL).UnixNano()},
+		}
+		result, err := cli.execResult(ctx, db, sql, values)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to reserve node ID")
+		}
+		if result.RowsAffected == 0 {
+			// Already reserved by someone else.
+			continue
+		}
+
+		return id, nil
+	}
+
+	return 0, fmt.Errorf("no free node ID found after %d attempts", nodeIDAttempts)
+}
+
+// Return a random non-zero node ID.
+func randomNodeID() (uint64, error) {
+	for {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return 0, errors.Wrap(err, "failed to generate node ID")
+		}
+		if id := binary.LittleEndian.Uint64(buf); id != 0 {
+			return id, nil
+		}
+	}
+}
+
+const reservationsTable = `CREATE TABLE IF NOT EXISTS node_id_reservations (
+  id INTEGER PRIMARY KEY,
+  expires INTEGER NOT NULL
+)`
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Approximate amount of SQL text executed in a single BulkLoad transaction.
+const bulkLoadBatchSize = 1024 * 1024
+
+// BulkLoad executes the SQL statements read from r against the given
+// database, grouping them in large transactions to minimize round trips.
+//
+// Statements must be separated by semicolons. Semicolons inside string
+// literals, quoted identifiers and comments are handled, but statements
+// containing other semicolons, such as CREATE TRIGGER, are not supported.
+// Statements must not begin or end transactions themselves.
+//
+// The load as a whole is NOT atomic: a transaction is committed each time
+// about a megabyte of statements has been read, and if an error occurs the
+// transactions committed so far are kept.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) BulkLoad(ctx context.Context, dbname string, r io.Reader) error {
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	db, err := cli.open(ctx, dbname)
+	if err != nil {
+		return err
+	}
+
+	batch := strings.Builder{}
+	batches := 0
+
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		sql := "BEGIN;\n" + batch.String() + "COMMIT;"
+		batch.Reset()
+		batches++
+		if err := cli.exec(ctx, db, sql, nil); err != nil {
+			// Best effort, the transaction might be gone already.
+			cli.exec(ctx, db, "ROLLBACK", nil)
+			return errors.Wrapf(err, "load batch %d", batches)
+		}
+		return nil
+	}
+
+	scanner := newStatementScanner(r)
+	for {
+		stmt, err := scanner.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "read statements")
+		}
+		batch.WriteString(stmt)
+		batch.WriteString("\n")
+		if batch.Len() >= bulkLoadBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// Splits a stream of SQL text into statements terminated by semicolons.
+type statementScanner struct {
+	r    *bufio.Reader
+	stmt strings.Builder
+}
+
+func newStatementScanner(r io.Reader) *statementScanner {
+	return &statementScanner{r: bufio.NewReader(r)}
+}
+
+// Next returns the next statement, including its terminating semicolon, or
+// io.EOF if there are no more statements. A trailing statement without
+// semicolon is terminated automatically.
+func (s *statementScanner) Next() (string, error) {
+	s.stmt.Reset()
+	terminator := ";"
+	for {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			stmt := strings.TrimSpace(s.stmt.String())
+			if stmt == "" {
+				return "", io.EOF
+			}
+			return stmt + terminator, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		s.stmt.WriteByte(c)
+
+		switch c {
+		case ';':
+			stmt := strings.TrimSpace(s.stmt.String())
+			if stmt == ";" {
+				// Empty statement.
+				s.stmt.Reset()
+				continue
+			}
+			return stmt, nil
+		case '\'', '"', '`':
+			err = s.copyUntil(string(c))
+		case '[':
+			err = s.copyUntil("]")
+		case '-':
+			if s.peek('-') {
+				err = s.copyUntil("\n")
+				if err == io.EOF {
+					// Don't comment out the terminator.
+					terminator = "\n;"
+				}
+			}
+		case '/':
+			if s.peek('*') {
+				err = s.copyUntil("*/")
+			}
+		}
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+	}
+}
+
+// Return true if the next byte is the given one, consuming it.
+func (s *statementScanner) peek(c byte) bool {
+	next, err := s.r.ReadByte()
+	if err != nil {
+		return false
+	}
+	if next != c {
+		s.r.UnreadByte()
+		return false
+	}
+	s.stmt.WriteByte(next)
+	return true
+}
+
+// Copy bytes into the current statement up to and including the given
+// terminator, or until the end of the stream.
+func (s *statementScanner) copyUntil(end string) error {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		s.stmt.WriteByte(c)
+		if c == end[len(end)-1] && strings.HasSuffix(s.stmt.String(), end) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package client_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/canonical/go-dqlite/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// All statements are sent in a single exec request, instead of one request
+// per statement.
+func TestClient_BulkLoad(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	execs := 0
+	log := func(l client.LogLevel, format string, a ...interface{}) {
+		if strings.HasPrefix(fmt.Sprintf(format, a...), "wire: send exec-sql request") {
+			execs++
+		}
+	}
+
+	cli, err := client.New(ctx, node.BindAddress(), client.WithWireLog(log))
+	require.NoError(t, err)
+	defer cli.Close()
+
+	const n = 2000
+
+	sql := strings.Builder{}
+	sql.WriteString("CREATE TABLE bulk (n INT, s TEXT);\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sql, "INSERT INTO bulk VALUES (%d, 'row; %d');\n", i, i)
+	}
+
+	require.NoError(t, cli.BulkLoad(ctx, "test.db", strings.NewReader(sql.String())))
+	assert.Equal(t, 1, execs)
+
+	request := protocol.Message{}
+	request.Init(4096)
+	response := protocol.Message{}
+	response.Init(4096)
+
+	p := cli.Protocol()
+
+	protocol.EncodeOpen(&request, "test.db", 0, "volatile")
+	require.NoError(t, p.Call(ctx, &request, &response))
+	db, err := protocol.DecodeDb(&response)
+	require.NoError(t, err)
+
+	protocol.EncodeQuerySQLV0(&request, uint64(db), "SELECT count(*) FROM bulk", nil)
+	require.NoError(t, p.Call(ctx, &request, &response))
+	rows, err := protocol.DecodeRows(&response)
+	require.NoError(t, err)
+	dest := make([]driver.Value, 1)
+	require.NoError(t, rows.Next(dest))
+	assert.Equal(t, int64(n), dest[0])
+	assert.Equal(t, io.EOF, rows.Next(dest))
+	rows.Close()
+}
+
+func TestClient_BulkLoadError(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	sql := "CREATE TABLE bulk (n INT);\nINSERT INTO bulk VALUES (1);\nINSERT INTO missing VALUES (2);\n"
+	err = cli.BulkLoad(ctx, "test.db", strings.NewReader(sql))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "load batch 1")
+}
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		title string
+		sql   string
+		stmts []string
+	}{
+		{
+			"empty",
+			" \n ",
+			[]string{},
+		},
+		{
+			"single",
+			"SELECT 1;",
+			[]string{"SELECT 1;"},
+		},
+		{
+			"missing trailing semicolon",
+			"SELECT 1;\nSELECT 2",
+			[]string{"SELECT 1;", "SELECT 2;"},
+		},
+		{
+			"empty statements",
+			";; SELECT 1;;",
+			[]string{"SELECT 1;"},
+		},
+		{
+			"string literal",
+			"INSERT INTO t VALUES ('a;b''c');SELECT 1;",
+			[]string{"INSERT INTO t VALUES ('a;b''c');", "SELECT 1;"},
+		},
+		{
+			"quoted identifiers",
+			"SELECT \"a;\", `b;`, [c;] FROM t;",
+			[]string{"SELECT \"a;\", `b;`, [c;] FROM t;"},
+		},
+		{
+			"comments",
+			"SELECT 1; -- a; b\nSELECT /* c; */ 2;",
+			[]string{"SELECT 1;", "-- a; b\nSELECT /* c; */ 2;"},
+		},
+		{
+			"trailing comment",
+			"SELECT 1 -- end",
+			[]string{"SELECT 1 -- end\n;"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			stmts, err := client.SplitStatements(strings.NewReader(c.sql))
+			require.NoError(t, err)
+			assert.Equal(t, c.stmts, stmts)
+		})
+	}
+}
@@ -2,6 +2,11 @@ package client
 
 import (
 	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/canonical/go-dqlite/internal/protocol"
 	"github.com/pkg/errors"
@@ -11,16 +16,35 @@ import (
 type DialFunc = protocol.DialFunc
 
 // Client speaks the dqlite wire protocol.
+//
+// Methods that open databases, such as Schema, BulkLoad or Vacuum, don't use
+// the client's own connection: each call opens a private connection to the
+// same node, using the same options, and closes it before returning. So they
+// can be called any number of times, regardless of whether a database was
+// opened on the client's own connection, and they leave that connection as
+// it is.
 type Client struct {
 	protocol *protocol.Protocol
+	observer *leaderObserver
+	address  string   // Address of the node we're connected to.
+	options  []Option // Used to open private connections.
+
+	pollInterval time.Duration // Used by WaitForRole.
+	sorted       bool          // Whether Cluster sorts its result.
 }
 
 // Option that can be used to tweak client parameters.
 type Option func(*options)
 
 type options struct {
-	DialFunc DialFunc
-	LogFunc  LogFunc
+	DialFunc     DialFunc
+	LogFunc      LogFunc
+	PollInterval time.Duration
+	Sorted       bool
+	LeaderCache  *LeaderCache
+	WireLog      LogFunc
+	Resolver     AddressResolver
+	SplitBudget  bool
 }
 
 // WithDialFunc sets a custom dial function for creating the client network
@@ -39,6 +63,62 @@ func WithLogFunc(log LogFunc) Option {
 	}
 }
 
+// WithPollInterval sets how often WaitForRole checks the cluster
+// configuration, and how often Migrate retries while another client is
+// applying migrations.
+//
+// If not used, the default is 100 milliseconds.
+func WithPollInterval(interval time.Duration) Option {
+	return func(options *options) {
+		options.PollInterval = interval
+	}
+}
+
+// WithSortedResults makes Cluster return nodes sorted with SortNodes, instead
+// of in the order they are sent by the server.
+func WithSortedResults() Option {
+	return func(options *options) {
+		options.Sorted = true
+	}
+}
+
+// WithLeaderCache makes FindLeader try the leader remembered in the given
+// cache first, before iterating the nodes in the store. The cache is updated
+// every time a leader is found, and cleared if the remembered node turns out
+// not to be the leader anymore.
+func WithLeaderCache(cache *LeaderCache) Option {
+	return func(options *options) {
+		options.LeaderCache = cache
+	}
+}
+
+// WithWireLog sets a log function that receives the type and size of every
+// message exchanged with the server, at debug level. Message contents are
+// never logged.
+func WithWireLog(log LogFunc) Option {
+	return func(options *options) {
+		options.WireLog = log
+	}
+}
+
+// WithAddressResolver makes FindLeader use the given resolver to look up the
+// address of nodes that the store holds only by ID, and of leaders reported
+// only by ID.
+func WithAddressResolver(resolver AddressResolver) Option {
+	return func(options *options) {
+		options.Resolver = resolver
+	}
+}
+
+// WithSplitAttemptBudget makes FindLeader split the time left before the
+// context deadline evenly among the nodes it still has to probe in each
+// attempt, so a few slow nodes can't consume the whole budget.
+func WithSplitAttemptBudget() Option {
+	return func(options *options) {
+		options.SplitBudget = true
+	}
+}
+
 // New creates a new client connected to the dqlite node with the given
 // address.
 func New(ctx context.Context, address string, options ...Option) (*Client, error) {
@@ -47,9 +127,17 @@ func New(ctx context.Context, address string, options ...Option) (*Client, error
 	for _, option := range options {
 		option(o)
 	}
+
+	target, ok := protocol.TargetFromContext(ctx)
+	if !ok || target.Address != address {
+		target = protocol.Target{Address: address}
+		ctx = protocol.WithTarget(ctx, target)
+	}
+
 	// Establish the connection.
 	conn, err := o.DialFunc(ctx, address)
 	if err != nil {
+		err = &protocol.ErrTarget{Target: target, Err: err}
 		return nil, errors.Wrap(err, "failed to establish network connection")
 	}
 
@@ -58,12 +146,32 @@ func New(ctx context.Context, address string, options ...Option) (*Client, error
 		conn.Close()
 		return nil, err
 	}
+	protocol.SetWireLog(o.WireLog)
 
-	client := &Client{protocol: protocol}
+	client := &Client{
+		protocol: protocol,
+		observer: newLeaderObserver(),
+		address:  address,
+		options:  options,
+
+		pollInterval: o.PollInterval,
+		sorted:       o.Sorted,
+	}
 
 	return client, nil
 }
 
+// Open a private connection to the same node as the client.
+func (c *Client) privateClient(ctx context.Context) (*Client, error) {
+	return New(ctx, c.address, c.privateOptions()...)
+}
+
+// Return the options to use for opening private connections, to the same node
+// as the client or to other nodes.
+func (c *Client) privateOptions() []Option {
+	return c.options
+}
+
 // Leader returns information about the current leader, if any.
 func (c *Client) Leader(ctx context.Context) (*NodeInfo, error) {
 	request := protocol.Message{}
@@ -84,10 +192,32 @@ func (c *Client) Leader(ctx context.Context) (*NodeInfo, error) {
 
 	info := &NodeInfo{ID: id, Address: address}
 
+	c.observer.observe(info)
+
 	return info, nil
 }
 
+// OnLeadershipChange registers a function that will be invoked whenever a
+// call to Leader() on this client reveals that the leader is different from
+// the one observed previously.
+//
+// The client does not watch the cluster by itself: changes are noticed only
+// through Leader() calls, nothing happens if they're not made.
+//
+// Changes are debounced: the function is invoked only once the leader reported
+// by the server has been stable for a short while, with the last leader that
+// the function was invoked with (or the first one observed) and the new one.
+// Replies reporting that no leader is currently known are ignored.
+//
+// Only one function can be registered, subsequent calls replace it.
+func (c *Client) OnLeadershipChange(f func(old, new *NodeInfo)) {
+	c.observer.setCallback(f)
+}
+
 // Cluster returns information about all nodes in the cluster.
+//
+// Nodes are returned in the order sent by the server, unless the client was
+// created with WithSortedResults.
 func (c *Client) Cluster(ctx context.Context) ([]NodeInfo, error) {
 	request := protocol.Message{}
 	request.Init(16)
@@ -105,15 +235,99 @@ func (c *Client) Cluster(ctx context.Context) ([]NodeInfo, error) {
 		return nil, errors.Wrap(err, "failed to parse Node response")
 	}
 
+	if c.sorted {
+		SortNodes(servers)
+	}
+
 	return servers, nil
 }
 
+// ClusterStream is like Cluster, but invokes the given function with each node
+// as soon as it's decoded, instead of returning all of them in a slice.
+//
+// If the function returns an error, iteration stops and the error is
+// returned. Nodes are always passed in the order sent by the server.
+func (c *Client) ClusterStream(ctx context.Context, f func(NodeInfo) error) error {
+	request := protocol.Message{}
+	request.Init(16)
+	response := protocol.Message{}
+	response.Init(512)
+
+	protocol.EncodeCluster(&request, protocol.ClusterFormatV1)
+
+	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+		return errors.Wrap(err, "failed to send Cluster request")
+	}
+
+	return protocol.DecodeNodesFunc(&response, f)
+}
+
 // File holds the content of a single database file.
 type File struct {
 	Name string
 	Data []byte
 }
 
+// SnapshotInfo holds metadata about a raft snapshot.
+type SnapshotInfo struct {
+	Index     uint64    // Index of the last raft log entry included in the snapshot.
+	Term      uint64    // Term of the last raft log entry included in the snapshot.
+	Timestamp time.Time // When the snapshot was taken.
+}
+
+// HealthReport describes the health of a single node.
+type HealthReport struct {
+	OK              bool      // True if all checks passed.
+	Started         bool      // Whether the node is serving requests.
+	LeaderReachable bool      // Whether the current leader could be contacted.
+	Leader          *NodeInfo // The current leader, if known.
+	Reason          string    // Why the node is unhealthy, if it is.
+}
+
+// PreflightResult holds the outcome of a single check run before starting a
+// node.
+type PreflightResult struct {
+	Name   string // Name of the check: "data-dir", "disk-space" or "bind-address".
+	OK     bool   // Whether the check passed.
+	Detail string // What was found, or how to fix it if the check failed.
+}
+
+// WaitForRole blocks until the node with the given ID shows up in the cluster
+// configuration with the given role, or the context is done.
+//
+// The cluster configuration is polled at the interval set with
+// WithPollInterval.
+func (c *Client) WaitForRole(ctx context.Context, id uint64, role NodeRole) error {
+	interval := c.pollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		nodes, err := c.Cluster(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return errors.Wrapf(ctx.Err(), "node %d did not become %s", id, role)
+			}
+			return err
+		}
+		for _, node := range nodes {
+			if node.ID == id && node.Role == role {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "node %d did not become %s", id, role)
+		case <-ticker.C:
+		}
+	}
+}
+
 // Dump the content of the database with the given name. Two files will be
 // returned, the first is the main database file (which has the same name as
 // the database), the second is the WAL file (which has the same name as the
@@ -149,6 +363,79 @@ func (c *Client) Dump(ctx context.Context, dbname string) ([]File, error) {
 	return dump, nil
 }
 
+// Schema returns the SQL text of the CREATE statements stored in the
+// sqlite_master table of the database with the given name, each terminated by
+// a semicolon and a newline.
+//
+// Since dqlite serves queries only on the leader, the client must be
+// connected to the current leader.
+func (c *Client) Schema(ctx context.Context, dbname string) (string, error) {
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	return cli.schema(ctx, dbname)
+}
+
+func (c *Client) schema(ctx context.Context, dbname string) (string, error) {
+	db, err := c.open(ctx, dbname)
+	if err != nil {
+		return "", err
+	}
+
+	request := protocol.Message{}
+	request.Init(4096)
+	response := protocol.Message{}
+	response.Init(4096)
+
+	sql := "SELECT sql FROM sqlite_master WHERE sql IS NOT NULL ORDER BY rowid"
+	protocol.EncodeQuerySQLV0(&request, uint64(db), sql, nil)
+
+	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+		return "", errors.Wrap(err, "failed to send query request")
+	}
+
+	rows, err := protocol.DecodeRows(&response)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse rows response")
+	}
+
+	var schema strings.Builder
+	row := make([]driver.Value, 1)
+
+	for {
+		err := rows.Next(row)
+		if err == protocol.ErrRowsPart {
+			rows.Close()
+			if err := c.protocol.More(ctx, &response); err != nil {
+				return "", errors.Wrap(err, "failed to receive more rows")
+			}
+			rows, err = protocol.DecodeRows(&response)
+			if err != nil {
+				return "", errors.Wrap(err, "failed to parse rows response")
+			}
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "failed to fetch row")
+		}
+		text, ok := row[0].(string)
+		if !ok {
+			return "", fmt.Errorf("unexpected schema column type %T", row[0])
+		}
+		schema.WriteString(text)
+		schema.WriteString(";\n")
+	}
+	rows.Close()
+
+	return schema.String(), nil
+}
+
 // Add a node to a cluster.
 //
 // The new node will have the role specified in node.Role. Note that if the
@@ -210,6 +497,41 @@ func (c *Client) Assign(ctx context.Context, id uint64, role NodeRole) error {
 	return nil
 }
 
+// ScaleOut adds the given nodes to the cluster and turns them into voters,
+// one at a time.
+//
+// All nodes are first added as spares. Each one is then promoted, and the
+// next promotion starts only after the previous node shows up as a voter in
+// the cluster configuration. Raft replicates the leader's log to a node
+// before granting it voting rights, so a promoted node is always caught up.
+// libdqlite doesn't report how far behind a node is, so no apply lag bound
+// can be enforced beyond that.
+//
+// The nodes being added must be online. The Role field of the given nodes is
+// ignored.
+//
+// This must be invoked on a client connected to the current leader. If an
+// error occurs the nodes added or promoted so far are left in place.
+func (c *Client) ScaleOut(ctx context.Context, nodes []NodeInfo) error {
+	for _, node := range nodes {
+		node.Role = Spare
+		if err := c.Add(ctx, node); err != nil {
+			return errors.Wrapf(err, "add node %d", node.ID)
+		}
+	}
+
+	for _, node := range nodes {
+		if err := c.Assign(ctx, node.ID, Voter); err != nil {
+			return errors.Wrapf(err, "promote node %d", node.ID)
+		}
+		if err := c.WaitForRole(ctx, node.ID, Voter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Transfer leadership from the current leader to another node.
 //
 // This must be invoked one client connected to the current leader.
@@ -307,13 +629,17 @@ func (c *Client) Weight(ctx context.Context, weight uint64) error {
 
 // Close the client.
 func (c *Client) Close() error {
+	c.observer.stop()
 	return c.protocol.Close()
 }
 
 // Create a client options object with sane defaults.
 func defaultOptions() *options {
 	return &options{
-		DialFunc: DefaultDialFunc,
-		LogFunc:  DefaultLogFunc,
+		DialFunc:     DefaultDialFunc,
+		LogFunc:      DefaultLogFunc,
+		PollInterval: defaultPollInterval,
 	}
 }
+
+const defaultPollInterval = 100 * time.Millisecond
@@ -1,9 +1,32 @@
 package client
 
 import (
+	"io"
+
 	"github.com/canonical/go-dqlite/internal/protocol"
 )
 
 func (c *Client) Protocol() *protocol.Protocol {
 	return c.protocol
 }
+
+// SplitStatements returns all statements read from r by a statementScanner.
+func SplitStatements(r io.Reader) ([]string, error) {
+	scanner := newStatementScanner(r)
+	stmts := []string{}
+	for {
+		stmt, err := scanner.Next()
+		if err == io.EOF {
+			return stmts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+// RebalanceStep exposes the planning step of Client.Rebalance.
+func RebalanceStep(nodes []NodeInfo, domains map[uint64]uint64, leaderID uint64) (NodeInfo, NodeInfo, bool) {
+	return rebalanceStep(nodes, domains, leaderID)
+}
@@ -2,15 +2,19 @@ package client_test
 
 import (
 	"context"
+	"database/sql/driver"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	dqlite "github.com/canonical/go-dqlite"
 	"github.com/canonical/go-dqlite/client"
 	"github.com/canonical/go-dqlite/internal/protocol"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -76,6 +80,55 @@ func TestClient_Dump(t *testing.T) {
 	assert.Equal(t, 8272, len(files[1].Data))
 }
 
+func TestClient_Schema(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	// Open a database and create a couple of test tables.
+	request := protocol.Message{}
+	request.Init(4096)
+
+	response := protocol.Message{}
+	response.Init(4096)
+
+	protocol.EncodeOpen(&request, "test.db", 0, "volatile")
+
+	p := cli.Protocol()
+	err = p.Call(ctx, &request, &response)
+	require.NoError(t, err)
+
+	db, err := protocol.DecodeDb(&response)
+	require.NoError(t, err)
+
+	protocol.EncodeExecSQLV0(&request, uint64(db), "CREATE TABLE foo (n INT)", nil)
+	err = p.Call(ctx, &request, &response)
+	require.NoError(t, err)
+
+	protocol.EncodeExecSQLV0(&request, uint64(db), "CREATE TABLE bar (s TEXT)", nil)
+	err = p.Call(ctx, &request, &response)
+	require.NoError(t, err)
+
+	// The client's own connection has already a database open, Schema uses
+	// a private one.
+	schema, err := cli.Schema(ctx, "test.db")
+	require.NoError(t, err)
+
+	assert.Contains(t, schema, "CREATE TABLE foo (n INT);")
+	assert.Contains(t, schema, "CREATE TABLE bar (s TEXT);")
+
+	// Calling Schema again works too.
+	again, err := cli.Schema(ctx, "test.db")
+	require.NoError(t, err)
+	assert.Equal(t, schema, again)
+}
+
 func TestClient_Cluster(t *testing.T) {
 	node, cleanup := newNode(t)
 	defer cleanup()
@@ -96,6 +149,34 @@ func TestClient_Cluster(t *testing.T) {
 	assert.Equal(t, servers[0].Role, client.Voter)
 }
 
+func TestClient_ClusterSorted(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress(), client.WithSortedResults())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	for _, id := range []uint64{4, 2, 3} {
+		_, cleanup := addNode(t, cli, id)
+		defer cleanup()
+	}
+
+	for i := 0; i < 3; i++ {
+		nodes, err := cli.Cluster(ctx)
+		require.NoError(t, err)
+
+		ids := []uint64{}
+		for _, node := range nodes {
+			ids = append(ids, node.ID)
+		}
+		assert.Equal(t, []uint64{1, 2, 3, 4}, ids)
+	}
+}
+
 func TestClient_Transfer(t *testing.T) {
 	node1, cleanup := newNode(t)
 	defer cleanup()
@@ -130,6 +211,386 @@ func TestClient_Transfer(t *testing.T) {
 
 }
 
+func TestClient_WaitForRole(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress(), client.WithPollInterval(20*time.Millisecond))
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, cleanup = addNode(t, cli, 2)
+	defer cleanup()
+
+	require.NoError(t, cli.WaitForRole(ctx, 2, client.Spare))
+
+	go func() {
+		cli, err := client.New(ctx, node.BindAddress())
+		if err != nil {
+			return
+		}
+		defer cli.Close()
+		cli.Assign(ctx, 2, client.Voter)
+	}()
+
+	require.NoError(t, cli.WaitForRole(ctx, 2, client.Voter))
+
+	// A role that is never reached makes the call return when the context
+	// is done.
+	short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	err = cli.WaitForRole(short, 2, client.StandBy)
+	assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+}
+
+func TestClient_ScaleOut(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress(), client.WithPollInterval(20*time.Millisecond))
+	require.NoError(t, err)
+	defer cli.Close()
+
+	nodes := []client.NodeInfo{}
+	for _, id := range []uint64{2, 3} {
+		dir, dirCleanup := newDir(t)
+		defer dirCleanup()
+
+		address := fmt.Sprintf("@%d", id+1000)
+		other, err := dqlite.New(id, address, dir, dqlite.WithBindAddress(address))
+		require.NoError(t, err)
+		require.NoError(t, other.Start())
+		defer other.Close()
+
+		nodes = append(nodes, client.NodeInfo{ID: id, Address: address})
+	}
+
+	// Keep querying the leader while scaling out.
+	done := make(chan struct{})
+	failures := make(chan error, 1)
+	go func() {
+		defer close(failures)
+		monitor, err := client.New(ctx, node.BindAddress())
+		if err != nil {
+			failures <- err
+			return
+		}
+		defer monitor.Close()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			leader, err := monitor.Leader(ctx)
+			if err == nil && leader == nil {
+				err = fmt.Errorf("no leader")
+			}
+			if err != nil {
+				failures <- err
+				return
+			}
+		}
+	}()
+
+	require.NoError(t, cli.ScaleOut(ctx, nodes))
+
+	close(done)
+	assert.NoError(t, <-failures)
+
+	servers, err := cli.Cluster(ctx)
+	require.NoError(t, err)
+	require.Len(t, servers, 3)
+	for _, server := range servers {
+		assert.Equal(t, client.Voter, server.Role)
+	}
+}
+
+func TestClient_OnLeadershipChange(t *testing.T) {
+	node1, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node1.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	node2, cleanup := addNode(t, cli, 2)
+	defer cleanup()
+
+	err = cli.Assign(ctx, 2, client.Voter)
+	require.NoError(t, err)
+
+	type change struct{ old, new uint64 }
+	changes := make(chan change, 1)
+
+	cli2, err := client.New(ctx, node2.BindAddress())
+	require.NoError(t, err)
+	defer cli2.Close()
+
+	cli2.OnLeadershipChange(func(old, new *client.NodeInfo) {
+		changes <- change{old: old.ID, new: new.ID}
+	})
+
+	leader, err := cli2.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), leader.ID)
+
+	err = cli.Transfer(ctx, 2)
+	require.NoError(t, err)
+
+	leader, err = cli2.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), leader.ID)
+
+	select {
+	case c := <-changes:
+		assert.Equal(t, change{old: 1, new: 2}, c)
+	case <-ctx.Done():
+		t.Fatal("leadership change not notified")
+	}
+}
+
+func TestClient_NewDialErrorTarget(t *testing.T) {
+	dial := func(ctx context.Context, address string) (net.Conn, error) {
+		target, ok := protocol.TargetFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "@1001", target.Address)
+		return nil, fmt.Errorf("boom")
+	}
+
+	_, err := client.New(context.Background(), "@1001", client.WithDialFunc(dial))
+	assert.EqualError(t, err, "failed to establish network connection: @1001: boom")
+}
+
+func TestClient_Maintenance(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	info, err := cli.Maintenance(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.MaintenanceInfo{}, *info)
+
+	require.NoError(t, cli.SetMaintenance(ctx, true))
+
+	info, err = cli.Maintenance(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.MaintenanceInfo{Enabled: true, LeaderID: 1}, *info)
+
+	require.NoError(t, cli.SetMaintenance(ctx, false))
+
+	info, err = cli.Maintenance(ctx)
+	require.NoError(t, err)
+	assert.False(t, info.Enabled)
+}
+
+func TestClient_LeadershipPriority(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	priority, err := cli.LeadershipPriority(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, priority)
+
+	require.NoError(t, cli.SetLeadershipPriority(ctx, 1, 10))
+	require.NoError(t, cli.SetLeadershipPriority(ctx, 2, 5))
+
+	priority, err = cli.LeadershipPriority(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 10, priority)
+
+	priority, err = cli.LeadershipPriority(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 5, priority)
+}
+
+func TestClient_NodeMetadata(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dir, dirCleanup := newDir(t)
+	defer dirCleanup()
+
+	other, err := dqlite.New(2, "@1002", dir, dqlite.WithBindAddress("@1002"))
+	require.NoError(t, err)
+	require.NoError(t, other.Start())
+	defer other.Close()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 2, Address: "@1002"}))
+	require.NoError(t, cli.Assign(ctx, 2, client.Voter))
+
+	kv, err := cli.GetNodeMetadata(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, kv)
+
+	labels := map[string]string{"region": "eu-west", "rack": "r12"}
+	require.NoError(t, cli.SetNodeMetadata(ctx, 1, labels))
+	require.NoError(t, cli.SetNodeMetadata(ctx, 2, map[string]string{"region": "us-east"}))
+
+	// Read the labels back starting from the other node.
+	store := client.NewInmemNodeStore()
+	require.NoError(t, store.Set(ctx, []client.NodeInfo{{Address: "@1002"}}))
+
+	leader, err := client.FindLeader(ctx, store)
+	require.NoError(t, err)
+	defer leader.Close()
+
+	kv, err = leader.GetNodeMetadata(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, labels, kv)
+
+	kv, err = leader.GetNodeMetadata(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "us-east"}, kv)
+
+	// Setting labels replaces the previous ones.
+	require.NoError(t, cli.SetNodeMetadata(ctx, 1, map[string]string{"rack": "r7"}))
+
+	kv, err = leader.GetNodeMetadata(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"rack": "r7"}, kv)
+}
+
+func TestClient_AllocateNodeID(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	ids := make(chan uint64, 2)
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			cli, err := client.New(ctx, node.BindAddress())
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer cli.Close()
+			id, err := cli.AllocateNodeID(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			ids <- id
+		}()
+	}
+
+	allocated := []uint64{}
+	for i := 0; i < 2; i++ {
+		select {
+		case id := <-ids:
+			allocated = append(allocated, id)
+		case err := <-errs:
+			t.Fatal(err)
+		}
+	}
+
+	assert.NotEqual(t, allocated[0], allocated[1])
+	for _, id := range allocated {
+		assert.NotEqual(t, uint64(0), id)
+		assert.NotEqual(t, uint64(1), id)
+	}
+
+	// An allocated ID can be used to add a node.
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: allocated[0], Address: "@1002"}))
+
+	id, err := cli.AllocateNodeID(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, allocated, id)
+}
+
+// Private connections log their messages to the client's wire log too.
+func TestClient_WireLogPrivateConnection(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	messages := []string{}
+	log := func(l client.LogLevel, format string, a ...interface{}) {
+		messages = append(messages, fmt.Sprintf(format, a...))
+	}
+
+	cli, err := client.New(ctx, node.BindAddress(), client.WithWireLog(log))
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, err = cli.SQLiteConfig(ctx, "test.db")
+	require.NoError(t, err)
+
+	opens := 0
+	for _, message := range messages {
+		if strings.HasPrefix(message, "wire: send open request") {
+			opens++
+		}
+	}
+	assert.Equal(t, 1, opens)
+}
+
+func TestClient_ExecReturning(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, _, err = cli.ExecReturning(ctx, "test.db", "CREATE TABLE test (id INTEGER PRIMARY KEY, t TEXT)", nil)
+	require.NoError(t, err)
+
+	args := []driver.NamedValue{{Ordinal: 1, Value: "a"}}
+	changes, rows, err := cli.ExecReturning(ctx, "test.db", "INSERT INTO test (t) VALUES (?) RETURNING id", args)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), changes)
+	assert.Equal(t, [][]driver.Value{{int64(1)}}, rows)
+
+	args = []driver.NamedValue{{Ordinal: 1, Value: "b"}}
+	changes, rows, err = cli.ExecReturning(ctx, "test.db", "INSERT INTO test (t) VALUES (?) RETURNING id, t", args)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), changes)
+	assert.Equal(t, [][]driver.Value{{int64(2), "b"}}, rows)
+}
+
 func TestClient_Describe(t *testing.T) {
 	node, cleanup := newNode(t)
 	defer cleanup()
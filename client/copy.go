@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CopyDatabase creates a new database with the given name, holding a copy of
+// the schema and contents of the source database.
+//
+// The source is read within a single read transaction, so the copy reflects a
+// consistent snapshot of it, and the copy is written within a single write
+// transaction, so it's either created in full or not at all. The two
+// databases are independent afterwards.
+//
+// An error is returned if the destination database already has any schema
+// object. Rows are copied by value, so tables without an INTEGER PRIMARY KEY
+// may get different rowids, and virtual tables are not supported. Since the
+// whole copy is a single raft entry, it should be used only for databases
+// that comfortably fit in memory.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) CopyDatabase(ctx context.Context, srcName, dstName string) error {
+	if srcName == dstName {
+		return fmt.Errorf("source and destination database are both %q", srcName)
+	}
+
+	src, err := c.privateClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	srcDB, err := src.open(ctx, srcName)
+	if err != nil {
+		return err
+	}
+
+	dst, err := c.privateClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	dstDB, err := dst.open(ctx, dstName)
+	if err != nil {
+		return err
+	}
+
+	row := make([]driver.Value, 1)
+	found, err := dst.queryRow(ctx, dstDB, "SELECT name FROM sqlite_master LIMIT 1", nil, row)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("database %q already exists", dstName)
+	}
+
+	if err := src.exec(ctx, srcDB, "BEGIN", nil); err != nil {
+		return errors.Wrap(err, "begin read transaction")
+	}
+	defer src.exec(ctx, srcDB, "ROLLBACK", nil)
+
+	objects := []schemaObject{}
+
+	sql := "SELECT type, name, sql FROM sqlite_master WHERE sql IS NOT NULL ORDER BY rowid"
+	err = src.queryRows(ctx, srcDB, sql, nil, func(row []driver.Value) error {
+		object := schemaObject{}
+		object.kind, _ = row[0].(string)
+		object.name, _ = row[1].(string)
+		object.sql, _ = row[2].(string)
+		if strings.HasPrefix(object.name, "sqlite_") {
+			return nil
+		}
+		if strings.HasPrefix(strings.ToUpper(object.sql), "CREATE VIRTUAL TABLE") {
+			return fmt.Errorf("virtual table %s is not supported", object.name)
+		}
+		objects = append(objects, object)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "read schema")
+	}
+
+	if err := dst.exec(ctx, dstDB, "BEGIN", nil); err != nil {
+		return errors.Wrap(err, "begin write transaction")
+	}
+
+	if err := copyObjects(ctx, src, srcDB, dst, dstDB, objects); err != nil {
+		// Best effort, the transaction might be gone already.
+		dst.exec(ctx, dstDB, "ROLLBACK", nil)
+		return err
+	}
+
+	if err := dst.exec(ctx, dstDB, "COMMIT", nil); err != nil {
+		return errors.Wrap(err, "commit copy")
+	}
+
+	return nil
+}
+
+// Entry of the sqlite_master table.
+type schemaObject struct {
+	kind string // Either table, index, view or trigger.
+	name string
+	sql  string
+}
+
+// Create the given schema objects in the destination database, copying the
+// rows of each table from the source database. Indexes, views and triggers
+// are created after all rows have been copied.
+func copyObjects(ctx context.Context, src *Client, srcDB uint32, dst *Client, dstDB uint32, objects []schemaObject) error {
+	for _, object := range objects {
+		if object.kind != "table" {
+			continue
+		}
+		if err := dst.exec(ctx, dstDB, object.sql, nil); err != nil {
+			return errors.Wrapf(err, "create table %s", object.name)
+		}
+		if err := copyRows(ctx, src, srcDB, dst, dstDB, object.name, object.name); err != nil {
+			return err
+		}
+	}
+
+	// Carry over AUTOINCREMENT counters, if any.
+	row := make([]driver.Value, 1)
+	sql := "SELECT name FROM sqlite_master WHERE name = 'sqlite_sequence'"
+	found, err := src.queryRow(ctx, srcDB, sql, nil, row)
+	if err != nil {
+		return err
+	}
+	if found {
+		if err := dst.exec(ctx, dstDB, "DELETE FROM sqlite_sequence", nil); err != nil {
+			return errors.Wrap(err, "reset sqlite_sequence")
+		}
+		if err := copyRows(ctx, src, srcDB, dst, dstDB, "sqlite_sequence", "sqlite_sequence"); err != nil {
+			return err
+		}
+	}
+
+	for _, object := range objects {
+		if object.kind == "table" {
+			continue
+		}
+		if err := dst.exec(ctx, dstDB, object.sql, nil); err != nil {
+			return errors.Wrapf(err, "create %s %s", object.kind, object.name)
+		}
+	}
+
+	return nil
+}
+
+// Insert all rows of the given source table into the given destination table.
+func copyRows(ctx context.Context, src *Client, srcDB uint32, dst *Client, dstDB uint32, srcTable, dstTable string) error {
+	query := fmt.Sprintf("SELECT * FROM %s", quoteIdentifier(srcTable))
+	insert := ""
+	err := src.queryRows(ctx, srcDB, query, nil, func(row []driver.Value) error {
+		if insert == "" {
+			params := strings.Repeat(", ?", len(row))[2:]
+			insert = fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoteIdentifier(dstTable), params)
+		}
+		values := make([]driver.NamedValue, len(row))
+		for i, value := range row {
+			values[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+		}
+		return dst.exec(ctx, dstDB, insert, values)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "copy rows of table %s", srcTable)
+	}
+	return nil
+}
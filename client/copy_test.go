@@ -0,0 +1,94 @@
+package client_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/canonical/go-dqlite/driver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CopyDatabase(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	schema := `
+CREATE TABLE test (id INTEGER PRIMARY KEY AUTOINCREMENT, n INT, s TEXT, b BLOB);
+CREATE INDEX test_n ON test (n);
+CREATE VIEW test_view AS SELECT n FROM test;
+INSERT INTO test (n, s, b) VALUES (1, 'one', x'01');
+INSERT INTO test (n, s, b) VALUES (2, 'two', NULL);
+INSERT INTO test (n, s, b) VALUES (3, NULL, x'0303');
+DELETE FROM test WHERE n = 3;
+`
+	require.NoError(t, cli.BulkLoad(ctx, "src.db", strings.NewReader(schema)))
+	require.NoError(t, cli.CopyDatabase(ctx, "src.db", "dst.db"))
+
+	store := client.NewInmemNodeStore()
+	require.NoError(t, store.Set(ctx, []client.NodeInfo{{Address: node.BindAddress()}}))
+	drv, err := driver.New(store)
+	require.NoError(t, err)
+	sql.Register("dqlite-copy", drv)
+
+	src, err := sql.Open("dqlite-copy", "src.db")
+	require.NoError(t, err)
+	defer src.Close()
+
+	dst, err := sql.Open("dqlite-copy", "dst.db")
+	require.NoError(t, err)
+	defer dst.Close()
+
+	dump := func(db *sql.DB) []string {
+		rows, err := db.Query("SELECT id, n, s, b FROM test ORDER BY id")
+		require.NoError(t, err)
+		defer rows.Close()
+		values := []string{}
+		for rows.Next() {
+			var id, n int64
+			var s sql.NullString
+			var b []byte
+			require.NoError(t, rows.Scan(&id, &n, &s, &b))
+			values = append(values, fmt.Sprintf("%d|%d|%s|%x", id, n, s.String, b))
+		}
+		require.NoError(t, rows.Err())
+		return values
+	}
+
+	assert.Equal(t, dump(src), dump(dst))
+
+	// The schema and the AUTOINCREMENT counter are copied too.
+	var count int
+	require.NoError(t, dst.QueryRow("SELECT count(*) FROM test_view").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	var name string
+	require.NoError(t, dst.QueryRow("SELECT name FROM sqlite_master WHERE type = 'index'").Scan(&name))
+	assert.Equal(t, "test_n", name)
+
+	_, err = dst.Exec("INSERT INTO test (n) VALUES (4)")
+	require.NoError(t, err)
+	var id int64
+	require.NoError(t, dst.QueryRow("SELECT id FROM test WHERE n = 4").Scan(&id))
+	assert.Equal(t, int64(4), id)
+
+	// The two databases are independent.
+	require.NoError(t, src.QueryRow("SELECT count(*) FROM test").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	// Copying onto an existing database fails.
+	err = cli.CopyDatabase(ctx, "src.db", "dst.db")
+	assert.EqualError(t, err, `database "dst.db" already exists`)
+}
@@ -3,9 +3,11 @@ package client
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"net"
 
 	"github.com/canonical/go-dqlite/internal/protocol"
+	"golang.org/x/sync/semaphore"
 )
 
 // DefaultDialFunc is the default dial function, which can handle plain TCP and
@@ -14,6 +16,14 @@ func DefaultDialFunc(ctx context.Context, address string) (net.Conn, error) {
 	return protocol.Dial(ctx, address)
 }
 
+// TCPDialFromAddr returns a dial function for TCP endpoints which sends
+// traffic out of the given local address, e.g. to pick a specific network
+// interface on a multi-homed host. The address can be either a bare IP or an
+// IP with a port.
+func TCPDialFromAddr(localAddr string) DialFunc {
+	return protocol.TCPDialFromAddr(localAddr)
+}
+
 // DialFuncWithTLS returns a dial function that uses TLS encryption.
 //
 // The given dial function will be used to establish the network connection,
@@ -35,3 +45,26 @@ func DialFuncWithTLS(dial DialFunc, config *tls.Config) DialFunc {
 		return tls.Client(conn, clonedConfig), nil
 	}
 }
+
+// LimitedDialFunc returns a dial function that allows at most maxConcurrent
+// dials with the given base dial function to be in progress at the same time.
+//
+// Excess dials wait until a slot frees up, or fail with the context error if
+// the context is done first. The limit only applies to establishing
+// connections, not to how many of them stay open.
+//
+// It panics if maxConcurrent is lower than 1, since no dial could ever
+// proceed.
+func LimitedDialFunc(base DialFunc, maxConcurrent int) DialFunc {
+	if maxConcurrent < 1 {
+		panic(fmt.Sprintf("invalid dial concurrency limit %d", maxConcurrent))
+	}
+	sem := semaphore.NewWeighted(int64(maxConcurrent))
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+		defer sem.Release(1)
+		return base(ctx, addr)
+	}
+}
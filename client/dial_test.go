@@ -0,0 +1,120 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitedDialFunc(t *testing.T) {
+	var current, peak int32
+	base := func(ctx context.Context, addr string) (net.Conn, error) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		conn, _ := net.Pipe()
+		return conn, nil
+	}
+
+	dial := client.LimitedDialFunc(base, 3)
+
+	n := 20
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			conn, err := dial(context.Background(), "@1")
+			if err == nil {
+				conn.Close()
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		assert.NoError(t, <-errs)
+	}
+
+	assert.Equal(t, int32(3), peak)
+}
+
+// A dial waiting for a free slot fails if its context is done.
+func TestLimitedDialFunc_ContextDone(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	base := func(ctx context.Context, addr string) (net.Conn, error) {
+		close(started)
+		<-release
+		conn, _ := net.Pipe()
+		return conn, nil
+	}
+
+	dial := client.LimitedDialFunc(base, 1)
+
+	errs := make(chan error, 1)
+	go func() {
+		conn, err := dial(context.Background(), "@1")
+		if err == nil {
+			conn.Close()
+		}
+		errs <- err
+	}()
+
+	// Wait for the first dial to grab the only slot.
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := dial(ctx, "@1")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	close(release)
+	assert.NoError(t, <-errs)
+}
+
+func TestLimitedDialFunc_InvalidLimit(t *testing.T) {
+	base := func(ctx context.Context, addr string) (net.Conn, error) {
+		return nil, nil
+	}
+	assert.Panics(t, func() { client.LimitedDialFunc(base, 0) })
+}
+
+func TestTCPDialFromAddr(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	dial := client.TCPDialFromAddr("127.0.0.1")
+
+	conn, err := dial(context.Background(), listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	local := conn.LocalAddr().(*net.TCPAddr)
+	assert.Equal(t, "127.0.0.1", local.IP.String())
+}
+
+func TestTCPDialFromAddr_InvalidAddress(t *testing.T) {
+	dial := client.TCPDialFromAddr("not an address")
+
+	_, err := dial(context.Background(), "127.0.0.1:9000")
+	assert.Error(t, err)
+}
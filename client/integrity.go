@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+// IntegrityCheck runs SQLite's integrity check against the database with the
+// given name and returns its result: a single "ok" entry if no problem was
+// found, or one entry per problem otherwise.
+//
+// The check is a read, so it runs on the leader without creating raft
+// entries. This must be invoked on a client connected to the current leader.
+func (c *Client) IntegrityCheck(ctx context.Context, dbname string) ([]string, error) {
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	db, err := cli.open(ctx, dbname)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []string{}
+	err = cli.queryRows(ctx, db, "PRAGMA integrity_check", nil, func(row []driver.Value) error {
+		text, ok := row[0].(string)
+		if !ok {
+			return fmt.Errorf("unexpected integrity check column type %T", row[0])
+		}
+		results = append(results, text)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,31 @@
+package client_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_IntegrityCheck(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	sql := "CREATE TABLE test (n INT);\nINSERT INTO test VALUES (1);\nCREATE INDEX test_n ON test (n);"
+	require.NoError(t, cli.BulkLoad(ctx, "test.db", strings.NewReader(sql)))
+
+	results, err := cli.IntegrityCheck(ctx, "test.db")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ok"}, results)
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// SetNodeMetadata replaces the labels attached to the node with the given ID,
+// such as its region or rack, with the given key/value pairs. An empty map
+// removes all labels of the node.
+//
+// Labels are stored in a replicated table of the SystemDatabase, so
+// they are visible cluster-wide and survive leader changes. Unlike the
+// failure domain and weight returned by Describe, they are not used by
+// dqlite itself and are meant for tooling.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) SetNodeMetadata(ctx context.Context, id uint64, kv map[string]string) error {
+	cli, db, err := c.openSystem(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	if err := cli.exec(ctx, db, "BEGIN", nil); err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+
+	if err := setNodeLabels(ctx, cli, db, id, kv); err != nil {
+		// Best effort, the transaction might be gone already.
+		cli.exec(ctx, db, "ROLLBACK", nil)
+		return err
+	}
+
+	if err := cli.exec(ctx, db, "COMMIT", nil); err != nil {
+		return errors.Wrap(err, "failed to commit node labels")
+	}
+
+	return nil
+}
+
+// GetNodeMetadata returns the labels attached to the node with the given ID,
+// or an empty map if none were set.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) GetNodeMetadata(ctx context.Context, id uint64) (map[string]string, error) {
+	cli, db, err := c.openSystem(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	kv := map[string]string{}
+
+	sql := "SELECT key, value FROM node_labels WHERE id = ?"
+	values := []driver.NamedValue{{Ordinal: 1, Value: int64(id)}}
+	err = cli.queryRows(ctx, db, sql, values, func(row []driver.Value) error {
+		key, ok := row[0].(string)
+		if !ok {
+			return fmt.Errorf("unexpected label key type %T", row[0])
+		}
+		value, ok := row[1].(string)
+		if !ok {
+			return fmt.Errorf("unexpected label value type %T", row[1])
+		}
+		kv[key] = value
+		return nil
+	})
+	if isMissingTable(err, "node_labels") {
+		return kv, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get node labels")
+	}
+
+	return kv, nil
+}
+
+// Replace the labels of the given node, within the current transaction.
+func setNodeLabels(ctx context.Context, cli *Client, db uint32, id uint64, kv map[string]string) error {
+	sql := "DELETE FROM node_labels WHERE id = ?"
+	values := []driver.NamedValue{{Ordinal: 1, Value: int64(id)}}
+	if err := cli.exec(ctx, db, sql, values); err != nil {
+		return errors.Wrap(err, "failed to clear node labels")
+	}
+
+	sql = "INSERT INTO node_labels (id, key, value) VALUES (?, ?, ?)"
+	for key, value := range kv {
+		values := []driver.NamedValue{
+			{Ordinal: 1, Value: int64(id)},
+			{Ordinal: 2, Value: key},
+			{Ordinal: 3, Value: value},
+		}
+		if err := cli.exec(ctx, db, sql, values); err != nil {
+			return errors.Wrapf(err, "failed to set node label %q", key)
+		}
+	}
+
+	return nil
+}
+
+const labelsTable = `CREATE TABLE IF NOT EXISTS node_labels (
+  id INTEGER NOT NULL,
+  key TEXT NOT NULL,
+  value TEXT NOT NULL,
+  PRIMARY KEY (id, key)
+)`
@@ -20,7 +20,11 @@ func FindLeader(ctx context.Context, store NodeStore, options ...Option) (*Clien
 	}
 
 	config := protocol.Config{
-		Dial: o.DialFunc,
+		Dial:            o.DialFunc,
+		LeaderCache:     o.LeaderCache,
+		WireLog:         o.WireLog,
+		AddressResolver: o.Resolver,
+		SplitBudget:     o.SplitBudget,
 	}
 	connector := protocol.NewConnector(0, store, config, o.LogFunc)
 	protocol, err := connector.Connect(ctx)
@@ -28,7 +32,16 @@ func FindLeader(ctx context.Context, store NodeStore, options ...Option) (*Clien
 		return nil, err
 	}
 
-	client := &Client{protocol: protocol}
+	client := &Client{
+		protocol:     protocol,
+		observer:     newLeaderObserver(),
+		options:      options,
+		pollInterval: o.PollInterval,
+		sorted:       o.Sorted,
+	}
+	if target, ok := protocol.Target(); ok {
+		client.address = target.Address
+	}
 
 	return client, nil
 }
@@ -10,7 +10,7 @@ type LogFunc = logging.Func
 // LogLevel defines the logging level.
 type LogLevel = logging.Level
 
-// Available logging levels.
+// Available logging levels, in order of increasing severity.
 const (
 	LogNone  = logging.None
 	LogDebug = logging.Debug
@@ -21,3 +21,30 @@ const (
 
 // DefaultLogFunc doesn't emit any message.
 func DefaultLogFunc(l LogLevel, format string, a ...interface{}) {}
+
+// MultiLogFunc returns a LogFunc that forwards each message to all the given
+// functions, in order.
+//
+// Nil functions are skipped. If one of the functions panics, the panic is
+// recovered and the message is still forwarded to the remaining ones.
+func MultiLogFunc(funcs ...LogFunc) LogFunc {
+	return func(l LogLevel, format string, a ...interface{}) {
+		for _, f := range funcs {
+			if f == nil {
+				continue
+			}
+			func() {
+				defer func() { recover() }()
+				f(l, format, a...)
+			}()
+		}
+	}
+}
+
+// LevelFilterLogFunc returns a LogFunc that forwards to next only the messages
+// whose level is equal to or more severe than min.
+//
+// Use logging.NewLevelFilter instead to change the level after creation.
+func LevelFilterLogFunc(min LogLevel, next LogFunc) LogFunc {
+	return logging.NewLevelFilter(next, min).Func()
+}
@@ -0,0 +1,46 @@
+package client_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiLogFunc(t *testing.T) {
+	messages1 := []string{}
+	messages2 := []string{}
+
+	log := client.MultiLogFunc(
+		newLogFunc(&messages1),
+		nil,
+		func(l client.LogLevel, format string, a ...interface{}) { panic("boom") },
+		newLogFunc(&messages2),
+	)
+
+	log(client.LogInfo, "hello %s", "world")
+
+	assert.Equal(t, []string{"INFO: hello world"}, messages1)
+	assert.Equal(t, []string{"INFO: hello world"}, messages2)
+}
+
+func TestLevelFilterLogFunc(t *testing.T) {
+	messages := []string{}
+
+	log := client.LevelFilterLogFunc(client.LogInfo, newLogFunc(&messages))
+
+	log(client.LogDebug, "debug")
+	log(client.LogInfo, "info")
+	log(client.LogWarn, "warn")
+	log(client.LogError, "error")
+
+	assert.Equal(t, []string{"INFO: info", "WARN: warn", "ERROR: error"}, messages)
+}
+
+// Return a log function that appends messages to the given slice.
+func newLogFunc(messages *[]string) client.LogFunc {
+	return func(l client.LogLevel, format string, a ...interface{}) {
+		*messages = append(*messages, l.String()+": "+fmt.Sprintf(format, a...))
+	}
+}
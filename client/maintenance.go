@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+)
+
+// MaintenanceInfo holds the state of the cluster-wide maintenance flag.
+type MaintenanceInfo struct {
+	Enabled  bool   // Whether maintenance mode was turned on.
+	LeaderID uint64 // ID of the leader at the time the flag was last set.
+}
+
+// SetMaintenance turns the cluster-wide maintenance flag on or off.
+//
+// The flag is stored in a replicated table of the SystemDatabase, along
+// with the ID of the current leader. It's a cooperative mechanism: it doesn't
+// prevent explicit role changes, but nodes that manage roles automatically
+// (such as the ones created with the app package) don't promote or demote
+// nodes while it's on.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) SetMaintenance(ctx context.Context, on bool) error {
+	leader, err := c.Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	cli, db, err := c.openSystem(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	enabled := int64(0)
+	if on {
+		enabled = 1
+	}
+
+	sql := "INSERT OR REPLACE INTO maintenance (id, enabled, leader_id) VALUES (0, ?, ?)"
+	values := []driver.NamedValue{
+		{Ordinal: 1, Value: enabled},
+		{Ordinal: 2, Value: int64(leader.ID)},
+	}
+	if err := cli.exec(ctx, db, sql, values); err != nil {
+		return errors.Wrap(err, "failed to set maintenance flag")
+	}
+
+	return nil
+}
+
+// Maintenance returns the current state of the cluster-wide maintenance flag.
+//
+// If the flag was never set, a zero MaintenanceInfo is returned. This must be
+// invoked on a client connected to the current leader.
+func (c *Client) Maintenance(ctx context.Context) (*MaintenanceInfo, error) {
+	cli, db, err := c.openSystem(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	info := &MaintenanceInfo{}
+	row := make([]driver.Value, 2)
+
+	sql := "SELECT enabled, leader_id FROM maintenance WHERE id = 0"
+	found, err := cli.queryRow(ctx, db, sql, nil, row)
+	if isMissingTable(err, "maintenance") {
+		return info, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return info, nil
+	}
+
+	enabled, _ := row[0].(int64)
+	leader, _ := row[1].(int64)
+	info.Enabled = enabled != 0
+	info.LeaderID = uint64(leader)
+
+	return info, nil
+}
+
+const maintenanceTable = `CREATE TABLE IF NOT EXISTS maintenance (
+  id INTEGER PRIMARY KEY CHECK (id = 0),
+  enabled INTEGER NOT NULL,
+  leader_id INTEGER NOT NULL
+)`
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Migration is a versioned change to the schema of a database.
+type Migration struct {
+	Version int    // Positive version number, unique within a database.
+	SQL     string // One or more statements, separated by semicolons.
+}
+
+// Migrate applies to the database with the given name the migrations that
+// were not applied yet, in the given order.
+//
+// Applied versions are recorded in the dqlite_migrations table of the
+// database itself. Each migration runs in its own write transaction along
+// with the update of that table, so it's either applied and recorded, or
+// not at all. Migrations must be given in increasing version order, and
+// their SQL must not begin or end transactions.
+//
+// It's safe to call Migrate concurrently from multiple clients: while a
+// client is applying a migration, the others wait for the write lock to be
+// released, retrying at the interval set with WithPollInterval, and then
+// skip the migrations that were applied in the meantime.
+//
+// This must be invoked on a client connected to the current leader. If an
+// error occurs the migrations applied so far are kept.
+func (c *Client) Migrate(ctx context.Context, dbname string, migrations []Migration) error {
+	for i, migration := range migrations {
+		if migration.Version <= 0 {
+			return fmt.Errorf("migration %d has non-positive version %d", i, migration.Version)
+		}
+		if i > 0 && migration.Version <= migrations[i-1].Version {
+			return fmt.Errorf("migration version %d is not greater than %d", migration.Version, migrations[i-1].Version)
+		}
+	}
+
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	db, err := cli.open(ctx, dbname)
+	if err != nil {
+		return err
+	}
+
+	if err := cli.exec(ctx, db, migrationsTable, nil); err != nil {
+		return errors.Wrap(err, "failed to create migrations table")
+	}
+
+	interval := c.pollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+
+	for _, migration := range migrations {
+		if err := beginImmediate(ctx, cli, db, interval); err != nil {
+			return errors.Wrapf(err, "migration %d", migration.Version)
+		}
+		if err := applyMigration(ctx, cli, db, migration); err != nil {
+			// Best effort, the transaction might be gone already.
+			cli.exec(ctx, db, "ROLLBACK", nil)
+			return errors.Wrapf(err, "migration %d", migration.Version)
+		}
+		if err := cli.exec(ctx, db, "COMMIT", nil); err != nil {
+			return errors.Wrapf(err, "commit migration %d", migration.Version)
+		}
+	}
+
+	return nil
+}
+
+// Start a write transaction, waiting for other connections holding the write
+// lock to release it.
+func beginImmediate(ctx context.Context, cli *Client, db uint32, interval time.Duration) error {
+	for {
+		err := cli.exec(ctx, db, "BEGIN IMMEDIATE", nil)
+		if err == nil {
+			return nil
+		}
+		if !isBusy(err) {
+			return errors.Wrap(err, "begin transaction")
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "wait for write lock")
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Apply the given migration within the current transaction, unless it was
+// already recorded.
+func applyMigration(ctx context.Context, cli *Client, db uint32, migration Migration) error {
+	row := make([]driver.Value, 1)
+	sql := "SELECT version FROM dqlite_migrations WHERE version = ?"
+	values := []driver.NamedValue{{Ordinal: 1, Value: int64(migration.Version)}}
+	found, err := cli.queryRow(ctx, db, sql, values, row)
+	if err != nil {
+		return err
+	}
+	if found {
+		return nil
+	}
+
+	if err := cli.exec(ctx, db, migration.SQL, nil); err != nil {
+		return err
+	}
+
+	sql = "INSERT INTO dqlite_migrations (version, applied_at) VALUES (?, ?)"
+	values = []driver.NamedValue{
+		{Ordinal: 1, Value: int64(migration.Version)},
+		{Ordinal: 2, Value: time.Now().Unix()},
+	}
+	if err := cli.exec(ctx, db, sql, values); err != nil {
+		return errors.Wrap(err, "record version")
+	}
+
+	return nil
+}
+
+const migrationsTable = `CREATE TABLE IF NOT EXISTS dqlite_migrations (
+  version INTEGER PRIMARY KEY,
+  applied_at INTEGER NOT NULL
+)`
@@ -0,0 +1,91 @@
+package client_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/canonical/go-dqlite/driver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Migrate(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress(), client.WithPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer cli.Close()
+
+	// Each migration records a row, so applying it twice would show.
+	migrations := []client.Migration{
+		{Version: 1, SQL: "CREATE TABLE runs (version INT); INSERT INTO runs VALUES (1)"},
+		{Version: 2, SQL: "CREATE TABLE test (n INT); INSERT INTO runs VALUES (2)"},
+		{Version: 3, SQL: "ALTER TABLE test ADD COLUMN s TEXT; INSERT INTO runs VALUES (3)"},
+	}
+
+	require.NoError(t, cli.Migrate(ctx, "test.db", migrations[:2]))
+
+	// Apply all migrations concurrently from several clients.
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			errs <- cli.Migrate(ctx, "test.db", migrations)
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	require.NoError(t, cli.Migrate(ctx, "test.db", migrations))
+
+	store := client.NewInmemNodeStore()
+	require.NoError(t, store.Set(ctx, []client.NodeInfo{{Address: node.BindAddress()}}))
+	drv, err := driver.New(store)
+	require.NoError(t, err)
+	sql.Register("dqlite-migrate", drv)
+
+	db, err := sql.Open("dqlite-migrate", "test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT version FROM runs ORDER BY rowid")
+	require.NoError(t, err)
+	defer rows.Close()
+	runs := []int{}
+	for rows.Next() {
+		var version int
+		require.NoError(t, rows.Scan(&version))
+		runs = append(runs, version)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []int{1, 2, 3}, runs)
+
+	_, err = db.Exec("INSERT INTO test (n, s) VALUES (1, 'x')")
+	assert.NoError(t, err)
+}
+
+func TestClient_MigrateOutOfOrder(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	migrations := []client.Migration{
+		{Version: 2, SQL: "CREATE TABLE test (n INT)"},
+		{Version: 1, SQL: "CREATE TABLE other (n INT)"},
+	}
+	err = cli.Migrate(ctx, "test.db", migrations)
+	assert.EqualError(t, err, "migration version 1 is not greater than 2")
+}
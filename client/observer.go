@@ -0,0 +1,82 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// How long the leader must be stable before OnLeadershipChange callbacks fire.
+const leadershipChangeDebounce = 250 * time.Millisecond
+
+// Track the leader reported by a client's Leader() calls and notify a callback
+// when it changes.
+type leaderObserver struct {
+	mu       sync.Mutex
+	debounce time.Duration
+	callback func(old, new *NodeInfo)
+	reported *NodeInfo   // Last leader passed to the callback, or first one observed.
+	current  *NodeInfo   // Last leader observed.
+	timer    *time.Timer // Pending notification, if any.
+	stopped  bool
+}
+
+func newLeaderObserver() *leaderObserver {
+	return &leaderObserver{debounce: leadershipChangeDebounce}
+}
+
+func (o *leaderObserver) setCallback(f func(old, new *NodeInfo)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.callback = f
+}
+
+func (o *leaderObserver) observe(info *NodeInfo) {
+	if info.ID == 0 {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.stopped {
+		return
+	}
+
+	leader := *info
+	o.current = &leader
+
+	if o.reported == nil || o.callback == nil {
+		o.reported = o.current
+		return
+	}
+
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+	o.timer = time.AfterFunc(o.debounce, o.notify)
+}
+
+func (o *leaderObserver) notify() {
+	o.mu.Lock()
+	if o.stopped || o.current.ID == o.reported.ID {
+		o.mu.Unlock()
+		return
+	}
+	old, new := o.reported, o.current
+	o.reported = o.current
+	callback := o.callback
+	o.mu.Unlock()
+
+	if callback != nil {
+		callback(old, new)
+	}
+}
+
+func (o *leaderObserver) stop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stopped = true
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+// SQLiteConfig holds the SQLite settings in effect for a database.
+type SQLiteConfig struct {
+	PageSize    int64  // Size of a database page in bytes.
+	CacheSize   int64  // Suggested cache size, in pages if positive or KiB if negative.
+	JournalMode string // Journal mode, normally "wal" for dqlite databases.
+	Synchronous int64  // Synchronous flag: 0 for OFF, 1 for NORMAL, 2 for FULL, 3 for EXTRA.
+}
+
+// SQLiteConfig returns the SQLite settings in effect for the database with
+// the given name, as reported by the corresponding PRAGMAs.
+//
+// The settings are read from a new connection opened by the leader. Since
+// cache_size and synchronous are per-connection settings, changes made by
+// running PRAGMAs on other connections are not reflected.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) SQLiteConfig(ctx context.Context, dbname string) (*SQLiteConfig, error) {
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	db, err := cli.open(ctx, dbname)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &SQLiteConfig{}
+	pragmas := []struct {
+		name  string
+		value interface{}
+	}{
+		{"page_size", &config.PageSize},
+		{"cache_size", &config.CacheSize},
+		{"journal_mode", &config.JournalMode},
+		{"synchronous", &config.Synchronous},
+	}
+
+	row := make([]driver.Value, 1)
+	for _, pragma := range pragmas {
+		found, err := cli.queryRow(ctx, db, "PRAGMA "+pragma.name, nil, row)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("no value for PRAGMA %s", pragma.name)
+		}
+		switch value := pragma.value.(type) {
+		case *int64:
+			n, ok := row[0].(int64)
+			if !ok {
+				return nil, fmt.Errorf("unexpected PRAGMA %s value type %T", pragma.name, row[0])
+			}
+			*value = n
+		case *string:
+			s, ok := row[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected PRAGMA %s value type %T", pragma.name, row[0])
+			}
+			*value = s
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+)
+
+// SetLeadershipPriority records the leadership priority of the node with the
+// given ID.
+//
+// Priorities are stored in a replicated table of the SystemDatabase and
+// are only advisory: nodes created with a leadership priority use them to
+// decide whether to reclaim leadership from the current leader.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) SetLeadershipPriority(ctx context.Context, id uint64, priority int) error {
+	cli, db, err := c.openSystem(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	sql := "INSERT OR REPLACE INTO leadership_priority (id, priority) VALUES (?, ?)"
+	values := []driver.NamedValue{
+		{Ordinal: 1, Value: int64(id)},
+		{Ordinal: 2, Value: int64(priority)},
+	}
+	if err := cli.exec(ctx, db, sql, values); err != nil {
+		return errors.Wrap(err, "failed to set leadership priority")
+	}
+
+	return nil
+}
+
+// LeadershipPriority returns the leadership priority recorded for the node with
+// the given ID, or zero if none was recorded.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) LeadershipPriority(ctx context.Context, id uint64) (int, error) {
+	cli, db, err := c.openSystem(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	defer cli.Close()
+
+	row := make([]driver.Value, 1)
+
+	sql := "SELECT priority FROM leadership_priority WHERE id = ?"
+	values := []driver.NamedValue{{Ordinal: 1, Value: int64(id)}}
+	found, err := cli.queryRow(ctx, db, sql, values, row)
+	if isMissingTable(err, "leadership_priority") {
+		return 0, nil
+	}
+	if err != nil || !found {
+		return 0, err
+	}
+
+	priority, _ := row[0].(int64)
+
+	return int(priority), nil
+}
+
+const priorityTable = `CREATE TABLE IF NOT EXISTS leadership_priority (
+  id INTEGER PRIMARY KEY,
+  priority INTEGER NOT NULL
+)`
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Rebalance spreads the voters of the cluster as evenly as possible across
+// failure domains, keeping the number of voters unchanged.
+//
+// The failure domain of each node is fetched by connecting to it and calling
+// Describe, so all nodes must be online. At each step a stand-by or spare in
+// the domain with the fewest voters is promoted. Then a voter in the domain
+// with the most voters takes the role the promoted node had. The promotion
+// comes first, so the number of voters never drops, and the demotion waits
+// for it to show up in the cluster configuration. The leader is never
+// demoted. Steps are repeated until no domain has two voters more than
+// another domain with a candidate for promotion. If the cluster is already
+// balanced, no change is made.
+//
+// This must be invoked on a client connected to the current leader. If an
+// error occurs the steps performed so far are left in place.
+func (c *Client) Rebalance(ctx context.Context) error {
+	leader, err := c.Leader(ctx)
+	if err != nil {
+		return err
+	}
+	if leader == nil {
+		return errors.New("no leader found")
+	}
+
+	nodes, err := c.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	domains := make(map[uint64]uint64, len(nodes))
+	for _, node := range nodes {
+		cli, err := New(ctx, node.Address, c.privateOptions()...)
+		if err != nil {
+			return errors.Wrapf(err, "connect to node %d", node.ID)
+		}
+		metadata, err := cli.Describe(ctx)
+		cli.Close()
+		if err != nil {
+			return errors.Wrapf(err, "describe node %d", node.ID)
+		}
+		domains[node.ID] = metadata.FailureDomain
+	}
+
+	for {
+		promote, demote, ok := rebalanceStep(nodes, domains, leader.ID)
+		if !ok {
+			return nil
+		}
+
+		if err := c.Assign(ctx, promote.ID, Voter); err != nil {
+			return errors.Wrapf(err, "promote node %d", promote.ID)
+		}
+		if err := c.WaitForRole(ctx, promote.ID, Voter); err != nil {
+			return err
+		}
+		if err := c.Assign(ctx, demote.ID, promote.Role); err != nil {
+			return errors.Wrapf(err, "demote node %d", demote.ID)
+		}
+
+		for i, node := range nodes {
+			switch node.ID {
+			case promote.ID:
+				nodes[i].Role = Voter
+			case demote.ID:
+				nodes[i].Role = promote.Role
+			}
+		}
+	}
+}
+
+// Pick the next node to promote to voter and the voter that should take its
+// role, or return false if voters are as balanced as they can be.
+func rebalanceStep(nodes []NodeInfo, domains map[uint64]uint64, leaderID uint64) (NodeInfo, NodeInfo, bool) {
+	voters := map[uint64]int{}
+	for _, node := range nodes {
+		if node.Role == Voter {
+			voters[domains[node.ID]]++
+		}
+	}
+
+	// The voter to demote must come from the domain with the most voters,
+	// and the node to promote from the domain with the fewest. Stand-bys
+	// are preferred over spares since they are already caught up.
+	var demote, promote *NodeInfo
+	for i, node := range nodes {
+		count := voters[domains[node.ID]]
+		switch node.Role {
+		case Voter:
+			if node.ID == leaderID {
+				continue
+			}
+			if demote == nil || count > voters[domains[demote.ID]] {
+				demote = &nodes[i]
+			}
+		case StandBy, Spare:
+			if promote == nil {
+				promote = &nodes[i]
+				continue
+			}
+			current := voters[domains[promote.ID]]
+			if count < current || (count == current && node.Role == StandBy && promote.Role == Spare) {
+				promote = &nodes[i]
+			}
+		}
+	}
+
+	if demote == nil || promote == nil {
+		return NodeInfo{}, NodeInfo{}, false
+	}
+	if voters[domains[promote.ID]]+1 >= voters[domains[demote.ID]] {
+		return NodeInfo{}, NodeInfo{}, false
+	}
+
+	return *promote, *demote, true
+}
@@ -0,0 +1,147 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	dqlite "github.com/canonical/go-dqlite"
+	"github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Rebalance(t *testing.T) {
+	dir, dirCleanup := newDir(t)
+	defer dirCleanup()
+
+	// Node 1 is the leader, in domain 0 like the other two voters.
+	node, err := dqlite.New(1, "@1001", dir, dqlite.WithBindAddress("@1001"))
+	require.NoError(t, err)
+	require.NoError(t, node.Start())
+	defer node.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress(), client.WithPollInterval(20*time.Millisecond))
+	require.NoError(t, err)
+	defer cli.Close()
+
+	domains := map[uint64]uint64{2: 0, 3: 0, 4: 1, 5: 2}
+	for id := uint64(2); id <= 5; id++ {
+		dir, dirCleanup := newDir(t)
+		defer dirCleanup()
+
+		address := fmt.Sprintf("@%d", id+1000)
+		other, err := dqlite.New(id, address, dir, dqlite.WithBindAddress(address), dqlite.WithFailureDomain(domains[id]))
+		require.NoError(t, err)
+		require.NoError(t, other.Start())
+		defer other.Close()
+
+		require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: id, Address: address}))
+		if id <= 3 {
+			require.NoError(t, cli.Assign(ctx, id, client.Voter))
+			require.NoError(t, cli.WaitForRole(ctx, id, client.Voter))
+		}
+	}
+
+	require.NoError(t, cli.Rebalance(ctx))
+
+	nodes, err := cli.Cluster(ctx)
+	require.NoError(t, err)
+
+	voters := map[uint64]int{}
+	for _, node := range nodes {
+		if node.Role == client.Voter {
+			voters[domains[node.ID]]++
+		}
+	}
+	assert.Equal(t, map[uint64]int{0: 1, 1: 1, 2: 1}, voters)
+
+	// A second run is a no-op.
+	require.NoError(t, cli.Rebalance(ctx))
+
+	again, err := cli.Cluster(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, nodes, again)
+}
+
+func TestRebalanceStep(t *testing.T) {
+	cases := []struct {
+		title   string
+		nodes   []client.NodeInfo
+		domains map[uint64]uint64
+		promote uint64
+		demote  uint64
+	}{
+		{
+			"balanced",
+			[]client.NodeInfo{
+				{ID: 1, Role: client.Voter},
+				{ID: 2, Role: client.Voter},
+				{ID: 3, Role: client.Voter},
+				{ID: 4, Role: client.Spare},
+			},
+			map[uint64]uint64{1: 1, 2: 2, 3: 3, 4: 1},
+			0,
+			0,
+		},
+		{
+			"off by one",
+			[]client.NodeInfo{
+				{ID: 1, Role: client.Voter},
+				{ID: 2, Role: client.Voter},
+				{ID: 3, Role: client.Voter},
+				{ID: 4, Role: client.Spare},
+			},
+			map[uint64]uint64{1: 1, 2: 1, 3: 2, 4: 3},
+			0,
+			0,
+		},
+		{
+			"all voters in one domain",
+			[]client.NodeInfo{
+				{ID: 1, Role: client.Voter},
+				{ID: 2, Role: client.Voter},
+				{ID: 3, Role: client.Voter},
+				{ID: 4, Role: client.Spare},
+				{ID: 5, Role: client.StandBy},
+			},
+			map[uint64]uint64{4: 1, 5: 2},
+			5,
+			2,
+		},
+		{
+			"leader is not demoted",
+			[]client.NodeInfo{
+				{ID: 1, Role: client.Voter},
+				{ID: 2, Role: client.Voter},
+				{ID: 3, Role: client.Spare},
+			},
+			map[uint64]uint64{1: 1, 2: 2, 3: 3},
+			0,
+			0,
+		},
+		{
+			"no candidates",
+			[]client.NodeInfo{
+				{ID: 1, Role: client.Voter},
+				{ID: 2, Role: client.Voter},
+				{ID: 3, Role: client.Voter},
+			},
+			nil,
+			0,
+			0,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			promote, demote, ok := client.RebalanceStep(c.nodes, c.domains, 1)
+			assert.Equal(t, c.promote != 0, ok)
+			assert.Equal(t, c.promote, promote.ID)
+			assert.Equal(t, c.demote, demote.ID)
+		})
+	}
+}
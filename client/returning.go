@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+)
+
+// ExecReturning executes the given statement against the database with the
+// given name and returns both the number of rows it changed and the rows
+// produced by its RETURNING clause, if any.
+//
+// The driver's Exec discards returned rows and its Query doesn't report the
+// number of changed rows, so this is the way to get both for the same
+// statement.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) ExecReturning(ctx context.Context, dbname string, sql string, args []driver.NamedValue) (int64, [][]driver.Value, error) {
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cli.Close()
+
+	db, err := cli.open(ctx, dbname)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows := [][]driver.Value{}
+	err = cli.queryRows(ctx, db, sql, args, func(row []driver.Value) error {
+		rows = append(rows, append([]driver.Value(nil), row...))
+		return nil
+	})
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to execute statement")
+	}
+
+	// The statement ran on the leader connection of this private client,
+	// so changes() refers to it.
+	row := make([]driver.Value, 1)
+	if _, err := cli.queryRow(ctx, db, "SELECT changes()", nil, row); err != nil {
+		return 0, nil, errors.Wrap(err, "failed to get changed rows")
+	}
+	changes, _ := row[0].(int64)
+
+	return changes, rows, nil
+}
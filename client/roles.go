@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ExportRoles returns the role of each node in the cluster configuration, by
+// node ID.
+//
+// The given client must be connected to the current leader.
+func ExportRoles(ctx context.Context, cli *Client) (map[uint64]NodeRole, error) {
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[uint64]NodeRole, len(nodes))
+	for _, node := range nodes {
+		roles[node.ID] = node.Role
+	}
+
+	return roles, nil
+}
+
+// ApplyRoles assigns the given roles to the nodes with the matching IDs, as
+// returned by ExportRoles.
+//
+// Changes are staged so that the number of voters never drops below the
+// smaller of its current and final values: all promotions to voter are made
+// first, then changes between stand-by and spare, and demotions of voters
+// last. Each promotion waits for the node to show up as a voter before moving
+// on. If the leader itself must be demoted, leadership is first transferred
+// to another voter, and the demotion is made through the new leader.
+//
+// All nodes in roles must already be part of the cluster, and at least one of
+// them must end up being a voter. Nodes not in roles keep their current role.
+// If an error occurs the changes made so far are left in place.
+//
+// The given client must be connected to the current leader. If leadership had
+// to be transferred, the client is left connected to the former leader.
+func ApplyRoles(ctx context.Context, cli *Client, roles map[uint64]NodeRole) error {
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return err
+	}
+	if leader == nil {
+		return errors.New("no leader found")
+	}
+
+	current := make(map[uint64]NodeInfo, len(nodes))
+	for _, node := range nodes {
+		current[node.ID] = node
+	}
+
+	ids := make([]uint64, 0, len(roles))
+	voters := 0
+	for id, role := range roles {
+		if _, ok := current[id]; !ok {
+			return fmt.Errorf("node %d is not part of the cluster", id)
+		}
+		if role == Voter {
+			voters++
+		}
+		ids = append(ids, id)
+	}
+	for _, node := range nodes {
+		if _, ok := roles[node.ID]; !ok && node.Role == Voter {
+			voters++
+		}
+	}
+	if voters == 0 {
+		return errors.New("no voter left")
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// Promotions first.
+	for _, id := range ids {
+		if roles[id] != Voter || current[id].Role == Voter {
+			continue
+		}
+		if err := cli.Assign(ctx, id, Voter); err != nil {
+			return errors.Wrapf(err, "promote node %d", id)
+		}
+		if err := cli.WaitForRole(ctx, id, Voter); err != nil {
+			return err
+		}
+	}
+
+	// Then changes that don't involve voters.
+	for _, id := range ids {
+		if roles[id] == Voter || current[id].Role == Voter || current[id].Role == roles[id] {
+			continue
+		}
+		if err := cli.Assign(ctx, id, roles[id]); err != nil {
+			return errors.Wrapf(err, "assign role to node %d", id)
+		}
+	}
+
+	// Demotions last, leaving the leader for the very end.
+	demoteLeader := false
+	for _, id := range ids {
+		if roles[id] == Voter || current[id].Role != Voter {
+			continue
+		}
+		if id == leader.ID {
+			demoteLeader = true
+			continue
+		}
+		if err := cli.Assign(ctx, id, roles[id]); err != nil {
+			return errors.Wrapf(err, "demote node %d", id)
+		}
+	}
+	if !demoteLeader {
+		return nil
+	}
+
+	return demoteLeaderTo(ctx, cli, leader.ID, roles)
+}
+
+// Transfer leadership to one of the nodes that are voters according to roles,
+// then demote the former leader through the new one.
+func demoteLeaderTo(ctx context.Context, cli *Client, id uint64, roles map[uint64]NodeRole) error {
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	var target *NodeInfo
+	for i, node := range nodes {
+		if node.ID == id || node.Role != Voter {
+			continue
+		}
+		if role, ok := roles[node.ID]; ok && role != Voter {
+			continue
+		}
+		if target == nil || node.ID < target.ID {
+			target = &nodes[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no voter to transfer leadership of node %d to", id)
+	}
+
+	if err := cli.Transfer(ctx, target.ID); err != nil {
+		return errors.Wrapf(err, "transfer leadership to node %d", target.ID)
+	}
+
+	other, err := New(ctx, target.Address, cli.privateOptions()...)
+	if err != nil {
+		return errors.Wrapf(err, "connect to node %d", target.ID)
+	}
+	defer other.Close()
+
+	if err := other.Assign(ctx, id, roles[id]); err != nil {
+		return errors.Wrapf(err, "demote node %d", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	dqlite "github.com/canonical/go-dqlite"
+	"github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportApplyRoles(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	cleanups := []func(){}
+	defer func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}()
+
+	// Create two clusters with the same node IDs and different roles.
+	newCluster := func(base int, roles map[uint64]client.NodeRole) *client.Client {
+		var cli *client.Client
+		for id := uint64(1); id <= 3; id++ {
+			dir, dirCleanup := newDir(t)
+			cleanups = append(cleanups, dirCleanup)
+
+			address := fmt.Sprintf("@%d", base+int(id))
+			node, err := dqlite.New(id, address, dir, dqlite.WithBindAddress(address))
+			require.NoError(t, err)
+			require.NoError(t, node.Start())
+			cleanups = append(cleanups, func() { node.Close() })
+
+			if id == 1 {
+				cli, err = client.New(ctx, address, client.WithPollInterval(20*time.Millisecond))
+				require.NoError(t, err)
+				cleanups = append(cleanups, func() { cli.Close() })
+				continue
+			}
+			require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: id, Address: address}))
+			if roles[id] != client.Spare {
+				require.NoError(t, cli.Assign(ctx, id, roles[id]))
+			}
+		}
+		return cli
+	}
+
+	source := newCluster(1000, map[uint64]client.NodeRole{2: client.StandBy, 3: client.Voter})
+	target := newCluster(1010, map[uint64]client.NodeRole{2: client.Voter, 3: client.Spare})
+
+	require.NoError(t, source.WaitForRole(ctx, 3, client.Voter))
+
+	roles, err := client.ExportRoles(ctx, source)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]client.NodeRole{1: client.Voter, 2: client.StandBy, 3: client.Voter}, roles)
+
+	require.NoError(t, client.ApplyRoles(ctx, target, roles))
+
+	applied, err := client.ExportRoles(ctx, target)
+	require.NoError(t, err)
+	assert.Equal(t, roles, applied)
+
+	// Demoting the leader transfers leadership first.
+	roles = map[uint64]client.NodeRole{1: client.Spare, 2: client.Voter, 3: client.Voter}
+	require.NoError(t, client.ApplyRoles(ctx, target, roles))
+
+	store := client.NewInmemNodeStore()
+	require.NoError(t, store.Set(ctx, []client.NodeInfo{{Address: "@1012"}, {Address: "@1013"}}))
+	leader, err := client.FindLeader(ctx, store)
+	require.NoError(t, err)
+	defer leader.Close()
+
+	applied, err = client.ExportRoles(ctx, leader)
+	require.NoError(t, err)
+	assert.Equal(t, roles, applied)
+}
+
+func TestApplyRoles_UnknownNode(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	err = client.ApplyRoles(ctx, cli, map[uint64]client.NodeRole{1: client.Voter, 2: client.Voter})
+	assert.EqualError(t, err, "node 2 is not part of the cluster")
+}
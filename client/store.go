@@ -4,6 +4,7 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"sort"
 	"sync"
 
 	"github.com/google/renameio"
@@ -22,12 +23,122 @@ type NodeRole = protocol.NodeRole
 // NodeInfo holds information about a single server.
 type NodeInfo = protocol.NodeInfo
 
+// SortNodes sorts the given nodes in place by ID, breaking ties by Address.
+func SortNodes(nodes []NodeInfo) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].ID != nodes[j].ID {
+			return nodes[i].ID < nodes[j].ID
+		}
+		return nodes[i].Address < nodes[j].Address
+	})
+}
+
+// DiffMembership computes the changes needed to turn the current cluster
+// membership into the desired one, matching nodes by ID.
+//
+// Nodes only in desired are returned in toAdd and nodes only in current in
+// toRemove. Nodes in both whose role differs are returned in toReassign, with
+// their desired role. Addresses are not compared. Each result keeps the order
+// of the slice it comes from, and node IDs are expected to be unique within
+// each slice.
+func DiffMembership(current, desired []NodeInfo) (toAdd, toRemove, toReassign []NodeInfo) {
+	currentByID := make(map[uint64]NodeInfo, len(current))
+	for _, node := range current {
+		currentByID[node.ID] = node
+	}
+	desiredByID := make(map[uint64]NodeInfo, len(desired))
+	for _, node := range desired {
+		desiredByID[node.ID] = node
+	}
+
+	for _, node := range desired {
+		existing, ok := currentByID[node.ID]
+		if !ok {
+			toAdd = append(toAdd, node)
+		} else if existing.Role != node.Role {
+			toReassign = append(toReassign, node)
+		}
+	}
+	for _, node := range current {
+		if _, ok := desiredByID[node.ID]; !ok {
+			toRemove = append(toRemove, node)
+		}
+	}
+
+	return toAdd, toRemove, toReassign
+}
+
+// FaultTolerance returns how many voters of the given cluster can fail at the
+// same time while a quorum of voters remains available.
+func FaultTolerance(nodes []NodeInfo) int {
+	voters := 0
+	for _, node := range nodes {
+		if node.Role == Voter {
+			voters++
+		}
+	}
+	if voters == 0 {
+		return 0
+	}
+	return (voters - 1) / 2
+}
+
+// DomainFaultTolerance is like FaultTolerance, but returns how many whole
+// failure domains can fail at the same time, assuming the ones hosting the
+// most voters fail first.
+//
+// The domains map holds the failure domain of each node by ID, as reported by
+// Client.Describe. Nodes missing from the map belong to domain 0.
+func DomainFaultTolerance(nodes []NodeInfo, domains map[uint64]uint64) int {
+	voters := 0
+	counts := map[uint64]int{}
+	for _, node := range nodes {
+		if node.Role == Voter {
+			voters++
+			counts[domains[node.ID]]++
+		}
+	}
+
+	sizes := make([]int, 0, len(counts))
+	for _, count := range counts {
+		sizes = append(sizes, count)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+
+	quorum := voters/2 + 1
+	remaining := voters
+	failed := 0
+	for _, size := range sizes {
+		if remaining-size < quorum {
+			break
+		}
+		remaining -= size
+		failed++
+	}
+
+	return failed
+}
+
 // InmemNodeStore keeps the list of target dqlite nodes in memory.
 type InmemNodeStore = protocol.InmemNodeStore
 
 // NewInmemNodeStore creates NodeStore which stores its data in-memory.
 var NewInmemNodeStore = protocol.NewInmemNodeStore
 
+// LeaderCache remembers the last leader found by FindLeader.
+type LeaderCache = protocol.LeaderCache
+
+// NewLeaderCache creates a new empty LeaderCache.
+var NewLeaderCache = protocol.NewLeaderCache
+
+// AddressResolver looks up the current address of a node given its ID.
+type AddressResolver = protocol.AddressResolver
+
+// ErrNotLeader is returned when a request fails because the node it was sent
+// to is not the leader. Its Leader field holds the leader known by that node,
+// if any.
+type ErrNotLeader = protocol.ErrNotLeader
+
 // Persists a list addresses of dqlite nodes in a YAML file.
 type YamlNodeStore struct {
 	path    string
@@ -59,6 +59,202 @@ func TestDefaultNodeStore(t *testing.T) {
 		servers)
 }
 
+func TestSortNodes(t *testing.T) {
+	nodes := []client.NodeInfo{
+		{ID: 3, Address: "@3"},
+		{ID: 1, Address: "@1b"},
+		{ID: 2, Address: "@2"},
+		{ID: 1, Address: "@1a"},
+	}
+	client.SortNodes(nodes)
+
+	assert.Equal(t, []client.NodeInfo{
+		{ID: 1, Address: "@1a"},
+		{ID: 1, Address: "@1b"},
+		{ID: 2, Address: "@2"},
+		{ID: 3, Address: "@3"},
+	}, nodes)
+}
+
+func TestDiffMembership(t *testing.T) {
+	cases := []struct {
+		title      string
+		current    []client.NodeInfo
+		desired    []client.NodeInfo
+		toAdd      []client.NodeInfo
+		toRemove   []client.NodeInfo
+		toReassign []client.NodeInfo
+	}{
+		{
+			"no changes",
+			[]client.NodeInfo{{ID: 1, Address: "@1", Role: client.Voter}},
+			[]client.NodeInfo{{ID: 1, Address: "@1", Role: client.Voter}},
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"additions",
+			[]client.NodeInfo{{ID: 1, Address: "@1", Role: client.Voter}},
+			[]client.NodeInfo{
+				{ID: 1, Address: "@1", Role: client.Voter},
+				{ID: 2, Address: "@2", Role: client.Voter},
+				{ID: 3, Address: "@3", Role: client.Spare},
+			},
+			[]client.NodeInfo{
+				{ID: 2, Address: "@2", Role: client.Voter},
+				{ID: 3, Address: "@3", Role: client.Spare},
+			},
+			nil,
+			nil,
+		},
+		{
+			"removals",
+			[]client.NodeInfo{
+				{ID: 1, Address: "@1", Role: client.Voter},
+				{ID: 2, Address: "@2", Role: client.StandBy},
+			},
+			[]client.NodeInfo{{ID: 1, Address: "@1", Role: client.Voter}},
+			nil,
+			[]client.NodeInfo{{ID: 2, Address: "@2", Role: client.StandBy}},
+			nil,
+		},
+		{
+			"role changes",
+			[]client.NodeInfo{
+				{ID: 1, Address: "@1", Role: client.Voter},
+				{ID: 2, Address: "@2", Role: client.Spare},
+			},
+			[]client.NodeInfo{
+				{ID: 1, Address: "@1", Role: client.Voter},
+				{ID: 2, Address: "@2", Role: client.StandBy},
+			},
+			nil,
+			nil,
+			[]client.NodeInfo{{ID: 2, Address: "@2", Role: client.StandBy}},
+		},
+		{
+			"address changes are ignored",
+			[]client.NodeInfo{{ID: 1, Address: "@1", Role: client.Voter}},
+			[]client.NodeInfo{{ID: 1, Address: "@10", Role: client.Voter}},
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"mixed",
+			[]client.NodeInfo{
+				{ID: 1, Address: "@1", Role: client.Voter},
+				{ID: 2, Address: "@2", Role: client.Voter},
+				{ID: 3, Address: "@3", Role: client.Voter},
+			},
+			[]client.NodeInfo{
+				{ID: 4, Address: "@4", Role: client.Voter},
+				{ID: 3, Address: "@3", Role: client.Spare},
+				{ID: 1, Address: "@1", Role: client.Voter},
+			},
+			[]client.NodeInfo{{ID: 4, Address: "@4", Role: client.Voter}},
+			[]client.NodeInfo{{ID: 2, Address: "@2", Role: client.Voter}},
+			[]client.NodeInfo{{ID: 3, Address: "@3", Role: client.Spare}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			toAdd, toRemove, toReassign := client.DiffMembership(c.current, c.desired)
+			assert.Equal(t, c.toAdd, toAdd)
+			assert.Equal(t, c.toRemove, toRemove)
+			assert.Equal(t, c.toReassign, toReassign)
+		})
+	}
+}
+
+func TestFaultTolerance(t *testing.T) {
+	cases := []struct {
+		title     string
+		voters    int
+		others    int
+		tolerance int
+	}{
+		{"no voters", 0, 2, 0},
+		{"one voter", 1, 0, 0},
+		{"three voters", 3, 0, 1},
+		{"four voters", 4, 0, 1},
+		{"five voters", 5, 0, 2},
+		{"three voters and two stand-bys", 3, 2, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			nodes := []client.NodeInfo{}
+			for i := 0; i < c.voters; i++ {
+				nodes = append(nodes, client.NodeInfo{ID: uint64(len(nodes) + 1), Role: client.Voter})
+			}
+			for i := 0; i < c.others; i++ {
+				nodes = append(nodes, client.NodeInfo{ID: uint64(len(nodes) + 1), Role: client.StandBy})
+			}
+			assert.Equal(t, c.tolerance, client.FaultTolerance(nodes))
+		})
+	}
+}
+
+func TestDomainFaultTolerance(t *testing.T) {
+	voters := func(n int) []client.NodeInfo {
+		nodes := make([]client.NodeInfo, n)
+		for i := range nodes {
+			nodes[i] = client.NodeInfo{ID: uint64(i + 1), Role: client.Voter}
+		}
+		return nodes
+	}
+
+	cases := []struct {
+		title     string
+		nodes     []client.NodeInfo
+		domains   map[uint64]uint64
+		tolerance int
+	}{
+		{
+			"single domain",
+			voters(3),
+			nil,
+			0,
+		},
+		{
+			"one voter per domain",
+			voters(3),
+			map[uint64]uint64{1: 1, 2: 2, 3: 3},
+			1,
+		},
+		{
+			"unbalanced domains",
+			voters(5),
+			map[uint64]uint64{1: 1, 2: 1, 3: 1, 4: 2, 5: 3},
+			0,
+		},
+		{
+			"five voters in five domains",
+			voters(5),
+			map[uint64]uint64{1: 1, 2: 2, 3: 3, 4: 4, 5: 5},
+			2,
+		},
+		{
+			"five voters in three domains",
+			voters(5),
+			map[uint64]uint64{1: 1, 2: 1, 3: 2, 4: 2, 5: 3},
+			1,
+		},
+		{
+			"non-voters are ignored",
+			append(voters(3), client.NodeInfo{ID: 4, Role: client.Spare}),
+			map[uint64]uint64{1: 1, 2: 2, 3: 3, 4: 1},
+			1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			assert.Equal(t, c.tolerance, client.DomainFaultTolerance(c.nodes, c.domains))
+		})
+	}
+}
+
 func TestConfigMultiThread(t *testing.T) {
 	cleanup := dummyDBSetup(t)
 	defer cleanup()
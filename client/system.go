@@ -0,0 +1,231 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+
+	"github.com/canonical/go-dqlite/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// Name of the database holding the cluster-wide maintenance flag, leadership
+// priorities, node labels and node ID reservations.
+//
+// It's only created the first time one of them is set, so clusters that
+// don't use these features don't get one. Applications must not use a
+// database with this name for their own data.
+const SystemDatabase = "dqlite-system"
+
+// Version of the schema of the SystemDatabase, stored in its user_version.
+const systemSchemaVersion = 1
+
+// Tables of the SystemDatabase, created in one transaction when its
+// user_version is lower than systemSchemaVersion.
+var systemTables = []string{
+	maintenanceTable,
+	priorityTable,
+	labelsTable,
+	reservationsTable,
+}
+
+// Open the system database on a private client. If setup is true, also
+// create its tables, unless that was done already.
+//
+// Readers pass false and treat a missing table as empty, so that they never
+// write to the database.
+func (c *Client) openSystem(ctx context.Context, setup bool) (*Client, uint32, error) {
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	db, err := cli.open(ctx, SystemDatabase)
+	if err != nil {
+		cli.Close()
+		return nil, 0, err
+	}
+
+	if !setup {
+		return cli, db, nil
+	}
+
+	if err := setupSystem(ctx, cli, db); err != nil {
+		cli.Close()
+		return nil, 0, err
+	}
+
+	return cli, db, nil
+}
+
+// Create the tables of the system database, if its schema version is older
+// than systemSchemaVersion.
+func setupSystem(ctx context.Context, cli *Client, db uint32) error {
+	row := make([]driver.Value, 1)
+	if _, err := cli.queryRow(ctx, db, "PRAGMA user_version", nil, row); err != nil {
+		return errors.Wrap(err, "failed to get system schema version")
+	}
+	if version, _ := row[0].(int64); version >= systemSchemaVersion {
+		return nil
+	}
+
+	if err := cli.exec(ctx, db, "BEGIN", nil); err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+
+	// The tables are created with IF NOT EXISTS, in case another client
+	// is setting up the schema concurrently.
+	for _, table := range systemTables {
+		if err := cli.exec(ctx, db, table, nil); err != nil {
+			// Best effort, the transaction might be gone already.
+			cli.exec(ctx, db, "ROLLBACK", nil)
+			return errors.Wrap(err, "failed to create system table")
+		}
+	}
+
+	sql := fmt.Sprintf("PRAGMA user_version = %d", systemSchemaVersion)
+	if err := cli.exec(ctx, db, sql, nil); err != nil {
+		cli.exec(ctx, db, "ROLLBACK", nil)
+		return errors.Wrap(err, "failed to set system schema version")
+	}
+
+	if err := cli.exec(ctx, db, "COMMIT", nil); err != nil {
+		return errors.Wrap(err, "failed to commit system schema")
+	}
+
+	return nil
+}
+
+// Open the database with the given name on the client's connection.
+func (c *Client) open(ctx context.Context, dbname string) (uint32, error) {
+	request := protocol.Message{}
+	request.Init(4096)
+	response := protocol.Message{}
+	response.Init(4096)
+
+	protocol.EncodeOpen(&request, dbname, 0, "volatile")
+
+	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+		return 0, errors.Wrap(err, "failed to send open request")
+	}
+
+	db, err := protocol.DecodeDb(&response)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse db response")
+	}
+
+	return db, nil
+}
+
+// Execute the given statement against the given database.
+func (c *Client) exec(ctx context.Context, db uint32, sql string, values []driver.NamedValue) error {
+	_, err := c.execResult(ctx, db, sql, values)
+	return err
+}
+
+// Execute the given statement against the given database and return its
+// result.
+func (c *Client) execResult(ctx context.Context, db uint32, sql string, values []driver.NamedValue) (protocol.Result, error) {
+	request := protocol.Message{}
+	request.Init(4096)
+	response := protocol.Message{}
+	response.Init(4096)
+
+	protocol.EncodeExecSQLV0(&request, uint64(db), sql, values)
+
+	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+		return protocol.Result{}, errors.Wrap(err, "failed to send exec request")
+	}
+
+	result, err := protocol.DecodeResult(&response)
+	if err != nil {
+		return protocol.Result{}, errors.Wrap(err, "failed to parse result response")
+	}
+
+	return result, nil
+}
+
+// Run the given query against the given database and fetch its first row into
+// dest, returning false if there's no row.
+func (c *Client) queryRow(ctx context.Context, db uint32, sql string, values []driver.NamedValue, dest []driver.Value) (bool, error) {
+	request := protocol.Message{}
+	request.Init(4096)
+	response := protocol.Message{}
+	response.Init(4096)
+
+	protocol.EncodeQuerySQLV0(&request, uint64(db), sql, values)
+
+	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+		return false, errors.Wrap(err, "failed to send query request")
+	}
+
+	rows, err := protocol.DecodeRows(&response)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to parse rows response")
+	}
+	defer rows.Close()
+
+	err = rows.Next(dest)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to fetch row")
+	}
+
+	return true, nil
+}
+
+// Run the given query against the given database, invoking f with each row.
+//
+// The row slice passed to f has one value per result column and is reused
+// across calls.
+func (c *Client) queryRows(ctx context.Context, db uint32, sql string, values []driver.NamedValue, f func(row []driver.Value) error) error {
+	request := protocol.Message{}
+	request.Init(4096)
+	response := protocol.Message{}
+	response.Init(4096)
+
+	protocol.EncodeQuerySQLV0(&request, uint64(db), sql, values)
+
+	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+		return errors.Wrap(err, "failed to send query request")
+	}
+
+	rows, err := protocol.DecodeRows(&response)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse rows response")
+	}
+
+	row := make([]driver.Value, len(rows.Columns))
+
+	for {
+		err := rows.Next(row)
+		if err == protocol.ErrRowsPart {
+			rows.Close()
+			if err := c.protocol.More(ctx, &response); err != nil {
+				return errors.Wrap(err, "failed to receive more rows")
+			}
+			rows, err = protocol.DecodeRows(&response)
+			if err != nil {
+				return errors.Wrap(err, "failed to parse rows response")
+			}
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rows.Close()
+			return errors.Wrap(err, "failed to fetch row")
+		}
+		if err := f(row); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/canonical/go-dqlite/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// TableStat holds size information about a single table.
+type TableStat struct {
+	Name  string // Name of the table.
+	Rows  int64  // Number of rows, approximate if the table was analyzed.
+	Pages int64  // Number of pages used by the table, excluding its indexes.
+	Size  int64  // Number of bytes in those pages.
+}
+
+// TableStats returns size information about the tables of the database with
+// the given name, sorted by table name. Internal sqlite_ tables are excluded.
+//
+// Row counts are taken from the sqlite_stat1 table for tables that were
+// analyzed with ANALYZE, in which case they're as accurate as the last
+// analysis. Other tables are counted, which requires a full scan. Page counts
+// and sizes come from the dbstat virtual table, and are zero if the SQLite
+// library used by the node was built without it.
+//
+// The queries are reads, so they run on the leader without creating raft
+// entries. This must be invoked on a client connected to the current leader.
+func (c *Client) TableStats(ctx context.Context, dbname string) ([]TableStat, error) {
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	db, err := cli.open(ctx, dbname)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := []TableStat{}
+	analyzed := false
+
+	sql := "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name"
+	err = cli.queryRows(ctx, db, sql, nil, func(row []driver.Value) error {
+		name, _ := row[0].(string)
+		if name == "sqlite_stat1" {
+			analyzed = true
+		}
+		if !strings.HasPrefix(name, "sqlite_") {
+			stats = append(stats, TableStat{Name: name, Rows: -1})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list tables")
+	}
+
+	index := make(map[string]int, len(stats))
+	for i, stat := range stats {
+		index[stat.Name] = i
+	}
+
+	if analyzed {
+		// The first integer of each entry is the number of rows in the table.
+		sql := "SELECT tbl, stat FROM sqlite_stat1"
+		err := cli.queryRows(ctx, db, sql, nil, func(row []driver.Value) error {
+			name, _ := row[0].(string)
+			stat, _ := row[1].(string)
+			i, ok := index[name]
+			if !ok {
+				return nil
+			}
+			rows, err := strconv.ParseInt(strings.Fields(stat + " 0")[0], 10, 64)
+			if err == nil {
+				stats[i].Rows = rows
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "read sqlite_stat1")
+		}
+	}
+
+	for i := range stats {
+		if stats[i].Rows >= 0 {
+			continue
+		}
+		sql := fmt.Sprintf("SELECT count(*) FROM %s", quoteIdentifier(stats[i].Name))
+		row := make([]driver.Value, 1)
+		if _, err := cli.queryRow(ctx, db, sql, nil, row); err != nil {
+			return nil, errors.Wrapf(err, "count rows of table %s", stats[i].Name)
+		}
+		stats[i].Rows, _ = row[0].(int64)
+	}
+
+	sql = "SELECT name, count(*), sum(pgsize) FROM dbstat GROUP BY name"
+	err = cli.queryRows(ctx, db, sql, nil, func(row []driver.Value) error {
+		name, _ := row[0].(string)
+		i, ok := index[name]
+		if !ok {
+			return nil
+		}
+		stats[i].Pages, _ = row[1].(int64)
+		stats[i].Size, _ = row[2].(int64)
+		return nil
+	})
+	if err != nil && !isMissingTable(err, "dbstat") {
+		return nil, errors.Wrap(err, "read dbstat")
+	}
+
+	return stats, nil
+}
+
+// Quote the given SQL identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// Return true if the given error was caused by the table with the given name
+// not existing.
+func isMissingTable(err error, table string) bool {
+	e, ok := errors.Cause(err).(protocol.ErrRequest)
+	return ok && strings.Contains(e.Description, "no such table: "+table)
+}
@@ -0,0 +1,58 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_TableStats(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	sql := strings.Builder{}
+	sql.WriteString("CREATE TABLE small (n INT);\n")
+	sql.WriteString("CREATE TABLE large (n INT, s TEXT);\n")
+	sql.WriteString("CREATE INDEX large_n ON large (n);\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&sql, "INSERT INTO small VALUES (%d);\n", i)
+	}
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&sql, "INSERT INTO large VALUES (%d, '%s');\n", i, strings.Repeat("x", 100))
+	}
+	require.NoError(t, cli.BulkLoad(ctx, "test.db", strings.NewReader(sql.String())))
+
+	// Exact counts are used for tables that were never analyzed.
+	stats, err := cli.TableStats(ctx, "test.db")
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "large", stats[0].Name)
+	assert.Equal(t, int64(500), stats[0].Rows)
+	assert.Equal(t, "small", stats[1].Name)
+	assert.Equal(t, int64(10), stats[1].Rows)
+	if stats[0].Pages > 0 {
+		assert.True(t, stats[0].Size > stats[1].Size)
+	}
+
+	// Analyzed tables report the row count estimated by ANALYZE.
+	require.NoError(t, cli.BulkLoad(ctx, "test.db", strings.NewReader("ANALYZE")))
+
+	stats, err = cli.TableStats(ctx, "test.db")
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.InDelta(t, 500, stats[0].Rows, 50)
+	assert.InDelta(t, 10, stats[1].Rows, 1)
+}
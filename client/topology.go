@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Version of the binary topology format written by EncodeTopology.
+const topologyVersion = 1
+
+// Maximum size of a single encoded node record accepted by DecodeTopology.
+const topologyMaxRecordSize = 64 * 1024
+
+// EncodeTopology writes the given nodes to w using a compact binary format.
+//
+// The format starts with a version byte followed by the number of nodes as a
+// 4-byte word. Each node is then encoded as a record prefixed by its length as
+// a 4-byte word, holding the node ID (8 bytes), its role (1 byte) and its
+// address (length-prefixed with a 4-byte word). All words are little endian.
+//
+// The framing described above is fixed for all versions: future versions may
+// only append new fields at the end of node records, which older decoders
+// will skip since records are length-prefixed. For this reason DecodeTopology
+// accepts any version greater than or equal to 1.
+func EncodeTopology(w io.Writer, nodes []NodeInfo) error {
+	header := make([]byte, 5)
+	header[0] = topologyVersion
+	binary.LittleEndian.PutUint32(header[1:], uint32(len(nodes)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		size := 8 + 1 + 4 + len(node.Address)
+		record := make([]byte, 4+size)
+		binary.LittleEndian.PutUint32(record[0:], uint32(size))
+		binary.LittleEndian.PutUint64(record[4:], node.ID)
+		record[12] = uint8(node.Role)
+		binary.LittleEndian.PutUint32(record[13:], uint32(len(node.Address)))
+		copy(record[17:], node.Address)
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DecodeTopology reads nodes encoded with EncodeTopology from r.
+//
+// Node records larger than 64KiB are rejected.
+func DecodeTopology(r io.Reader) ([]NodeInfo, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrap(err, "read header")
+	}
+	if header[0] < topologyVersion {
+		return nil, errors.Errorf("unsupported topology version %d", header[0])
+	}
+	n := binary.LittleEndian.Uint32(header[1:])
+
+	// Don't trust the node count for allocating memory, since it comes
+	// from the input.
+	nodes := []NodeInfo{}
+	for i := uint32(0); i < n; i++ {
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, errors.Wrapf(err, "read node %d size", i)
+		}
+		if size < 8+1+4 {
+			return nil, errors.Errorf("node %d record too short (%d bytes)", i, size)
+		}
+		if size > topologyMaxRecordSize {
+			return nil, errors.Errorf("node %d record too long (%d bytes)", i, size)
+		}
+		record := make([]byte, size)
+		if _, err := io.ReadFull(r, record); err != nil {
+			return nil, errors.Wrapf(err, "read node %d", i)
+		}
+
+		node, err := decodeTopologyRecord(record)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode node %d", i)
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// Decode a single node record, ignoring any trailing unknown field.
+func decodeTopologyRecord(record []byte) (NodeInfo, error) {
+	buf := bytes.NewReader(record)
+	node := NodeInfo{}
+	var role uint8
+	var length uint32
+
+	if err := binary.Read(buf, binary.LittleEndian, &node.ID); err != nil {
+		return node, errors.Wrap(err, "read ID")
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &role); err != nil {
+		return node, errors.Wrap(err, "read role")
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
+		return node, errors.Wrap(err, "read address length")
+	}
+	if int64(length) > int64(buf.Len()) {
+		return node, errors.New("address overflows record")
+	}
+	address := make([]byte, length)
+	if _, err := io.ReadFull(buf, address); err != nil {
+		return node, errors.Wrap(err, "read address")
+	}
+
+	node.Role = NodeRole(role)
+	node.Address = string(address)
+
+	return node, nil
+}
@@ -0,0 +1,82 @@
+package client_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopology_RoundTrip(t *testing.T) {
+	nodes := []client.NodeInfo{
+		{ID: 1, Address: "1.2.3.4:666", Role: client.Voter},
+		{ID: 2, Address: "@abstract", Role: client.StandBy},
+		{ID: 0xffffffffffffffff, Address: "", Role: client.Spare},
+	}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, client.EncodeTopology(&buf, nodes))
+
+	decoded, err := client.DecodeTopology(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, nodes, decoded)
+}
+
+func TestTopology_Empty(t *testing.T) {
+	buf := bytes.Buffer{}
+	require.NoError(t, client.EncodeTopology(&buf, nil))
+	assert.Equal(t, []byte{1, 0, 0, 0, 0}, buf.Bytes())
+
+	decoded, err := client.DecodeTopology(&buf)
+	require.NoError(t, err)
+	assert.Empty(t, decoded)
+}
+
+// Fields appended to node records by future versions are skipped.
+func TestTopology_FutureFields(t *testing.T) {
+	data := []byte{
+		2,          // version
+		1, 0, 0, 0, // number of nodes
+		17, 0, 0, 0, // record size
+		7, 0, 0, 0, 0, 0, 0, 0, // ID
+		1,          // role
+		2, 0, 0, 0, // address length
+		'@', '1', // address
+		0xaa, 0xbb, // unknown field
+	}
+
+	nodes, err := client.DecodeTopology(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, []client.NodeInfo{{ID: 7, Address: "@1", Role: client.StandBy}}, nodes)
+}
+
+func TestTopology_Truncated(t *testing.T) {
+	nodes := []client.NodeInfo{{ID: 1, Address: "1.2.3.4:666", Role: client.Voter}}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, client.EncodeTopology(&buf, nodes))
+
+	data := buf.Bytes()
+	_, err := client.DecodeTopology(bytes.NewReader(data[:len(data)-1]))
+	assert.Error(t, err)
+}
+
+// A header announcing a huge number of nodes doesn't make the decoder allocate
+// memory upfront.
+func TestTopology_BogusHeader(t *testing.T) {
+	_, err := client.DecodeTopology(bytes.NewReader([]byte{1, 0xff, 0xff, 0xff, 0xff}))
+	assert.EqualError(t, err, "read node 0 size: EOF")
+}
+
+func TestTopology_RecordTooLong(t *testing.T) {
+	data := []byte{
+		1,          // version
+		1, 0, 0, 0, // number of nodes
+		0xff, 0xff, 0xff, 0xff, // record size
+	}
+
+	_, err := client.DecodeTopology(bytes.NewReader(data))
+	assert.EqualError(t, err, "node 0 record too long (4294967295 bytes)")
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+
+	"github.com/canonical/go-dqlite/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// ErrDatabaseBusy is returned by Vacuum when the database is locked by other
+// connections.
+var ErrDatabaseBusy = errors.New("database is busy")
+
+// SQLite primary result codes signaling lock contention.
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// Vacuum rebuilds the database with the given name, reclaiming the space left
+// unused by deleted rows.
+//
+// The VACUUM statement is replicated and committed like any other write. It
+// needs exclusive access to the database, so it fails with ErrDatabaseBusy if
+// a transaction is in progress on another connection, and while it runs other
+// writes to the database are blocked. Since it rewrites every page, on large
+// databases it produces a correspondingly large raft entry.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) Vacuum(ctx context.Context, dbname string) error {
+	cli, err := c.privateClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	db, err := cli.open(ctx, dbname)
+	if err != nil {
+		return err
+	}
+
+	if err := cli.exec(ctx, db, "VACUUM", nil); err != nil {
+		if isBusy(err) {
+			return errors.Wrapf(ErrDatabaseBusy, "vacuum %s", dbname)
+		}
+		return errors.Wrapf(err, "vacuum %s", dbname)
+	}
+
+	return nil
+}
+
+// Return true if the given error was caused by lock contention.
+func isBusy(err error) bool {
+	if e, ok := errors.Cause(err).(protocol.ErrRequest); ok {
+		switch e.Code & 0xff {
+		case sqliteBusy, sqliteLocked:
+			return true
+		}
+	}
+	return false
+}
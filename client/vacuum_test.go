@@ -0,0 +1,68 @@
+package client_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/canonical/go-dqlite/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Vacuum(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	sql := strings.Builder{}
+	sql.WriteString("CREATE TABLE test (n INT, s TEXT);\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&sql, "INSERT INTO test VALUES (%d, '%s');\n", i, strings.Repeat("x", 512))
+	}
+	require.NoError(t, cli.BulkLoad(ctx, "test.db", strings.NewReader(sql.String())))
+
+	request := protocol.Message{}
+	request.Init(4096)
+	response := protocol.Message{}
+	response.Init(4096)
+
+	p := cli.Protocol()
+
+	protocol.EncodeOpen(&request, "test.db", 0, "volatile")
+	require.NoError(t, p.Call(ctx, &request, &response))
+	db, err := protocol.DecodeDb(&response)
+	require.NoError(t, err)
+
+	protocol.EncodeExecSQLV0(&request, uint64(db), "DELETE FROM test WHERE n > 10", nil)
+	require.NoError(t, p.Call(ctx, &request, &response))
+
+	// dqlite keeps database files in memory, so the database size is
+	// measured in pages.
+	pageCount := func() int64 {
+		protocol.EncodeQuerySQLV0(&request, uint64(db), "PRAGMA page_count", nil)
+		require.NoError(t, p.Call(ctx, &request, &response))
+		rows, err := protocol.DecodeRows(&response)
+		require.NoError(t, err)
+		defer rows.Close()
+		row := make([]driver.Value, 1)
+		require.NoError(t, rows.Next(row))
+		return row[0].(int64)
+	}
+
+	before := pageCount()
+	require.NoError(t, cli.Vacuum(ctx, "test.db"))
+	after := pageCount()
+
+	assert.True(t, after < before, "page count %d not lower than %d", after, before)
+}
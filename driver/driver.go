@@ -147,6 +147,40 @@ func WithRetryLimit(limit uint) Option {
 	}
 }
 
+// WithMaxRetryDuration sets the maximum total amount of time spent retrying
+// to connect to the leader, regardless of the retry limit.
+//
+// When the duration elapses before a leader is found, the root cause of the
+// returned error is ErrRetryDeadlineExceeded.
+//
+// If not used, the default is 0 (no limit).
+func WithMaxRetryDuration(duration time.Duration) Option {
+	return func(options *options) {
+		options.MaxRetryDuration = duration
+	}
+}
+
+// WithSplitAttemptBudget makes each connection attempt split the time left
+// before the context deadline evenly among the servers it still has to
+// probe, instead of granting each one the full attempt timeout.
+//
+// With many servers in the store, this ensures several of them get probed
+// before the deadline, even if the first ones are slow to respond. The
+// timeout set with WithAttemptTimeout still applies as an upper bound.
+func WithSplitAttemptBudget() Option {
+	return func(options *options) {
+		options.SplitAttemptBudget = true
+	}
+}
+
+// WithAddressResolver sets a resolver used to look up the address of nodes
+// that the store holds only by ID.
+func WithAddressResolver(resolver client.AddressResolver) Option {
+	return func(options *options) {
+		options.AddressResolver = resolver
+	}
+}
+
 // WithContext sets a global cancellation context.
 //
 // DEPRECATED: This API is no a no-op. Users should explicitly pass a context
@@ -193,11 +227,14 @@ func New(store client.NodeStore, options ...Option) (*Driver, error) {
 		contextTimeout:    o.ContextTimeout,
 		tracing:           o.Tracing,
 		clientConfig: protocol.Config{
-			Dial:           o.Dial,
-			AttemptTimeout: o.AttemptTimeout,
-			BackoffFactor:  o.ConnectionBackoffFactor,
-			BackoffCap:     o.ConnectionBackoffCap,
-			RetryLimit:     o.RetryLimit,
+			Dial:             o.Dial,
+			AttemptTimeout:   o.AttemptTimeout,
+			BackoffFactor:    o.ConnectionBackoffFactor,
+			BackoffCap:       o.ConnectionBackoffCap,
+			RetryLimit:       o.RetryLimit,
+			MaxRetryDuration: o.MaxRetryDuration,
+			AddressResolver:  o.AddressResolver,
+			SplitBudget:      o.SplitAttemptBudget,
 		},
 	}
 
@@ -214,6 +251,9 @@ type options struct {
 	ConnectionBackoffFactor time.Duration
 	ConnectionBackoffCap    time.Duration
 	RetryLimit              uint
+	MaxRetryDuration        time.Duration
+	AddressResolver         client.AddressResolver
+	SplitAttemptBudget      bool
 	Context                 context.Context
 	Tracing                 client.LogLevel
 }
@@ -324,6 +364,10 @@ func (d *Driver) SetContextTimeout(timeout time.Duration) {}
 // leader available in the cluster.
 var ErrNoAvailableLeader = protocol.ErrNoAvailableLeader
 
+// ErrRetryDeadlineExceeded is returned as root cause of Open() if no leader
+// could be found within the duration set with WithMaxRetryDuration.
+var ErrRetryDeadlineExceeded = protocol.ErrRetryDeadlineExceeded
+
 // Conn implements the sql.Conn interface.
 type Conn struct {
 	log            client.LogFunc
@@ -379,7 +423,17 @@ func (c *Conn) Prepare(query string) (driver.Stmt, error) {
 }
 
 // ExecContext is an optional interface that may be implemented by a Conn.
+//
+// Rows produced by a RETURNING clause are discarded, since driver.Result can't
+// carry them: use QueryContext to execute such statements and read the
+// returned rows, or client.Client.ExecReturning to also get the number of
+// changed rows.
 func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	args, err := protocol.BindNamedValues(query, args)
+	if err != nil {
+		return nil, driverError(c.log, err)
+	}
+
 	if int64(len(args)) > math.MaxUint32 {
 		return nil, driverError(c.log, fmt.Errorf("too many parameters (%d)", len(args)))
 	} else if len(args) > math.MaxUint8 {
@@ -392,7 +446,7 @@ func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.Name
 	if c.tracing != client.LogNone {
 		start = time.Now()
 	}
-	err := c.protocol.Call(ctx, &c.request, &c.response)
+	err = c.protocol.Call(ctx, &c.request, &c.response)
 	if c.tracing != client.LogNone {
 		c.log(c.tracing, "%.3fs request exec: %q", time.Since(start).Seconds(), query)
 	}
@@ -416,6 +470,11 @@ func (c *Conn) Query(query string, args []driver.Value) (driver.Rows, error) {
 
 // QueryContext is an optional interface that may be implemented by a Conn.
 func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	args, err := protocol.BindNamedValues(query, args)
+	if err != nil {
+		return nil, driverError(c.log, err)
+	}
+
 	if int64(len(args)) > math.MaxUint32 {
 		return nil, driverError(c.log, fmt.Errorf("too many parameters (%d)", len(args)))
 	} else if len(args) > math.MaxUint8 {
@@ -428,7 +487,7 @@ func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 	if c.tracing != client.LogNone {
 		start = time.Now()
 	}
-	err := c.protocol.Call(ctx, &c.request, &c.response)
+	err = c.protocol.Call(ctx, &c.request, &c.response)
 	if c.tracing != client.LogNone {
 		c.log(c.tracing, "%.3fs request query: %q", time.Since(start).Seconds(), query)
 	}
@@ -574,8 +633,16 @@ func (s *Stmt) NumInput() int {
 // ExecContext executes a query that doesn't return rows, such
 // as an INSERT or UPDATE.
 //
+// Rows produced by a RETURNING clause are discarded: use QueryContext to read
+// them.
+//
 // ExecContext must honor the context timeout and return when it is canceled.
 func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	args, err := protocol.BindNamedValues(s.sql, args)
+	if err != nil {
+		return nil, driverError(s.log, err)
+	}
+
 	if int64(len(args)) > math.MaxUint32 {
 		return nil, driverError(s.log, fmt.Errorf("too many parameters (%d)", len(args)))
 	} else if len(args) > math.MaxUint8 {
@@ -588,7 +655,7 @@ func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (drive
 	if s.tracing != client.LogNone {
 		start = time.Now()
 	}
-	err := s.protocol.Call(ctx, s.request, s.response)
+	err = s.protocol.Call(ctx, s.request, s.response)
 	if s.tracing != client.LogNone {
 		s.log(s.tracing, "%.3fs request prepared: %q", time.Since(start).Seconds(), s.sql)
 	}
@@ -615,6 +682,11 @@ func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
 //
 // QueryContext must honor the context timeout and return when it is canceled.
 func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	args, err := protocol.BindNamedValues(s.sql, args)
+	if err != nil {
+		return nil, driverError(s.log, err)
+	}
+
 	if int64(len(args)) > math.MaxUint32 {
 		return nil, driverError(s.log, fmt.Errorf("too many parameters (%d)", len(args)))
 	} else if len(args) > math.MaxUint8 {
@@ -627,7 +699,7 @@ func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driv
 	if s.tracing != client.LogNone {
 		start = time.Now()
 	}
-	err := s.protocol.Call(ctx, s.request, s.response)
+	err = s.protocol.Call(ctx, s.request, s.response)
 	if s.tracing != client.LogNone {
 		s.log(s.tracing, "%.3fs request prepared: %q", time.Since(start).Seconds(), s.sql)
 	}
@@ -801,6 +873,12 @@ func driverError(log client.LogFunc, err error) error {
 	case *net.OpError:
 		log(client.LogDebug, "network connection lost: %v", err)
 		return driver.ErrBadConn
+	case *protocol.ErrPartialWrite:
+		log(client.LogDebug, "request partially sent: %v", err)
+		return driver.ErrBadConn
+	case *protocol.ErrNotLeader:
+		log(client.LogDebug, "not leader: %v", err)
+		return driver.ErrBadConn
 	case protocol.ErrRequest:
 		switch err.Code {
 		case errIoErrNotLeaderLegacy:
@@ -383,6 +383,89 @@ INSERT INTO test (n,t) VALUES (3,'b');
 	assert.NoError(t, conn.Close())
 }
 
+func TestConn_NamedParams(t *testing.T) {
+	drv, cleanup := newDriver(t)
+	defer cleanup()
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	execer := conn.(driver.ExecerContext)
+	ctx := context.Background()
+
+	_, err = execer.ExecContext(ctx, "CREATE TABLE test (n INT, t TEXT)", nil)
+	require.NoError(t, err)
+
+	args := []driver.NamedValue{
+		{Name: "text", Ordinal: 1, Value: "a"},
+		{Name: "num", Ordinal: 2, Value: int64(1)},
+	}
+	_, err = execer.ExecContext(ctx, "INSERT INTO test (n,t) VALUES (:num, @text)", args)
+	require.NoError(t, err)
+
+	queryer := conn.(driver.QueryerContext)
+
+	args = []driver.NamedValue{{Name: "num", Ordinal: 1, Value: int64(1)}}
+	rows, err := queryer.QueryContext(ctx, "SELECT n, t FROM test WHERE n = $num", args)
+	require.NoError(t, err)
+
+	values := make([]driver.Value, 2)
+	require.NoError(t, rows.Next(values))
+	assert.Equal(t, int64(1), values[0])
+	assert.Equal(t, "a", values[1])
+	require.NoError(t, rows.Close())
+
+	// Mixing named and positional arguments is rejected.
+	args = []driver.NamedValue{
+		{Name: "num", Ordinal: 1, Value: int64(1)},
+		{Ordinal: 2, Value: "a"},
+	}
+	_, err = execer.ExecContext(ctx, "INSERT INTO test (n,t) VALUES (:num, ?)", args)
+	assert.EqualError(t, err, "can't mix named and positional arguments")
+
+	assert.NoError(t, conn.Close())
+}
+
+// Statements with a RETURNING clause can be run as queries to read the
+// returned values.
+func TestConn_QueryReturning(t *testing.T) {
+	drv, cleanup := newDriver(t)
+	defer cleanup()
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	execer := conn.(driver.ExecerContext)
+	ctx := context.Background()
+
+	_, err = execer.ExecContext(ctx, "CREATE TABLE test (id INTEGER PRIMARY KEY, t TEXT)", nil)
+	require.NoError(t, err)
+
+	_, err = execer.ExecContext(ctx, "INSERT INTO test (t) VALUES ('a')", nil)
+	require.NoError(t, err)
+
+	queryer := conn.(driver.QueryerContext)
+
+	rows, err := queryer.QueryContext(ctx, "INSERT INTO test (t) VALUES ('b') RETURNING id, t", nil)
+	require.NoError(t, err)
+
+	values := make([]driver.Value, 2)
+	require.NoError(t, rows.Next(values))
+	assert.Equal(t, int64(2), values[0])
+	assert.Equal(t, "b", values[1])
+	assert.Equal(t, io.EOF, rows.Next(values))
+	require.NoError(t, rows.Close())
+
+	// The row was actually inserted.
+	rows, err = queryer.QueryContext(ctx, "SELECT count(*) FROM test", nil)
+	require.NoError(t, err)
+	require.NoError(t, rows.Next(values[:1]))
+	assert.Equal(t, int64(2), values[0])
+	require.NoError(t, rows.Close())
+
+	require.NoError(t, conn.Close())
+}
+
 func TestConn_QueryManyParams(t *testing.T) {
 	drv, cleanup := newDriver(t)
 	defer cleanup()
@@ -87,6 +87,25 @@ type SnapshotParams struct {
 	Trailing  uint64
 }
 
+// NodeInterface is the set of Node methods used by higher level packages, so
+// they can be unit tested against a fake implementation.
+type NodeInterface interface {
+	SetDialFunc(dial protocol.DialFunc) error
+	SetBindAddress(address string) error
+	SetNetworkLatency(nanoseconds uint64) error
+	SetSnapshotParams(params SnapshotParams) error
+	SetFailureDomain(code uint64) error
+	EnableDiskMode() error
+	SetAutoRecovery(on bool) error
+	GetBindAddress() string
+	Start() error
+	Stop() error
+	Close()
+	Recover(cluster []protocol.NodeInfo) error
+}
+
+var _ NodeInterface = (*Node)(nil)
+
 // Initializes state.
 func init() {
 	// FIXME: ignore SIGPIPE, see https://github.com/joyent/libuv/issues/1254
@@ -1,15 +1,61 @@
 package protocol
 
 import (
+	"context"
 	"time"
+
+	"github.com/canonical/go-dqlite/logging"
 )
 
 // Config holds various configuration parameters for a dqlite client.
 type Config struct {
-	Dial           DialFunc      // Network dialer.
-	DialTimeout    time.Duration // Timeout for establishing a network connection .
-	AttemptTimeout time.Duration // Timeout for each individual attempt to probe a server's leadership.
-	BackoffFactor  time.Duration // Exponential backoff factor for retries.
-	BackoffCap     time.Duration // Maximum connection retry backoff value,
-	RetryLimit     uint          // Maximum number of retries, or 0 for unlimited.
+	Dial             DialFunc        // Network dialer.
+	DialTimeout      time.Duration   // Timeout for establishing a network connection .
+	AttemptTimeout   time.Duration   // Timeout for each individual attempt to probe a server's leadership.
+	BackoffFactor    time.Duration   // Exponential backoff factor for retries.
+	BackoffCap       time.Duration   // Maximum connection retry backoff value,
+	RetryLimit       uint            // Maximum number of retries, or 0 for unlimited.
+	ReadBufferSize   int             // Socket receive buffer size for TCP connections, or 0 for the OS default.
+	WriteBufferSize  int             // Socket send buffer size for TCP connections, or 0 for the OS default.
+	MaxRetryDuration time.Duration   // Maximum total time spent retrying a connection, or 0 for unlimited.
+	LeaderCache      *LeaderCache    // Leader to try first, if not nil. Updated after each successful connection.
+	Clock            Clock           // Clock used for retry waits and deadlines, the real clock if nil.
+	WireLog          logging.Func    // Logs the type and size of every message, if set.
+	AddressResolver  AddressResolver // Resolves the address of nodes known only by ID, if set.
+	DisableNoDelay   bool            // Leave Nagle's algorithm on for TCP connections.
+	SplitBudget      bool            // Split the time left before the context deadline among the servers of each attempt.
+}
+
+// AddressResolver looks up the current address of a node given its ID.
+//
+// It's consulted by the Connector when a NodeStore entry has an ID but no
+// address, and when a server redirects to a leader without reporting its
+// address.
+type AddressResolver interface {
+	Resolve(ctx context.Context, id uint64) (string, error)
+}
+
+// Clock is used to tell the time and wait for time to pass, so tests can
+// control it.
+//
+// There's no blocking Sleep method: every wait must be cut short when the
+// context is canceled, so waits select on the channel returned by After.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel that receives the current time once the
+	// given duration has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// Clock implementation backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
 }
@@ -21,6 +21,12 @@ type DialFunc func(context.Context, string) (net.Conn, error)
 
 // Connector is in charge of creating a dqlite SQL client connected to the
 // current leader of a cluster.
+//
+// A single Connector can be shared and it's safe to call Connect from multiple
+// goroutines concurrently, provided that the NodeStore and the logging
+// function it was created with are safe for concurrent use as well. The
+// Connector state is never modified after NewConnector returns, except for
+// the LeaderCache in its Config, which is safe for concurrent use.
 type Connector struct {
 	id     uint64       // Conn ID to use when registering against the server.
 	store  NodeStore    // Used to get and update current cluster servers.
@@ -51,6 +57,10 @@ func NewConnector(id uint64, store NodeStore, config Config, log logging.Func) *
 		config.BackoffCap = time.Second
 	}
 
+	if config.Clock == nil {
+		config.Clock = realClock{}
+	}
+
 	connector := &Connector{
 		id:     id,
 		store:  store,
@@ -67,7 +77,14 @@ func NewConnector(id uint64, store NodeStore, config Config, log logging.Func) *
 func (c *Connector) Connect(ctx context.Context) (*Protocol, error) {
 	var protocol *Protocol
 
-	strategies := makeRetryStrategies(c.config.BackoffFactor, c.config.BackoffCap, c.config.RetryLimit)
+	// Bound the total time spent retrying, if configured. The deadline is
+	// tracked with the configured clock, like the backoff waits.
+	var deadline time.Time
+	if c.config.MaxRetryDuration != 0 {
+		deadline = c.config.Clock.Now().Add(c.config.MaxRetryDuration)
+	}
+
+	strategies := makeRetryStrategies(ctx, c.config.Clock, deadline, c.config.BackoffFactor, c.config.BackoffCap, c.config.RetryLimit)
 
 	// The retry strategy should be configured to retry indefinitely, until
 	// the given context is done.
@@ -84,8 +101,12 @@ func (c *Connector) Connect(ctx context.Context) (*Protocol, error) {
 		default:
 		}
 
+		if c.expired(deadline) {
+			return nil
+		}
+
 		var err error
-		protocol, err = c.connectAttemptAll(ctx, log)
+		protocol, err = c.connectAttemptAll(ctx, deadline, log)
 		if err != nil {
 			return err
 		}
@@ -93,6 +114,11 @@ func (c *Connector) Connect(ctx context.Context) (*Protocol, error) {
 		return nil
 	}, strategies...)
 
+	if protocol == nil && c.expired(deadline) && ctx.Err() == nil {
+		// We hit MaxRetryDuration, not the caller's deadline.
+		return nil, ErrRetryDeadlineExceeded
+	}
+
 	if err != nil {
 		// We exhausted the number of retries allowed by the configured
 		// strategy.
@@ -114,78 +140,173 @@ func (c *Connector) Connect(ctx context.Context) (*Protocol, error) {
 }
 
 // Make a single attempt to establish a connection to the leader server trying
-// all addresses available in the store.
-func (c *Connector) connectAttemptAll(ctx context.Context, log logging.Func) (*Protocol, error) {
+// the cached leader first, if any, and then all addresses available in the
+// store.
+func (c *Connector) connectAttemptAll(ctx context.Context, deadline time.Time, log logging.Func) (*Protocol, error) {
+	if cache := c.config.LeaderCache; cache != nil {
+		if address := cache.Get(); address != "" {
+			log := func(l logging.Level, format string, a ...interface{}) {
+				format = fmt.Sprintf("leader hint %s: ", address) + format
+				log(l, format, a...)
+			}
+			// With SplitBudget, the hint gets at most half of
+			// the time left, leaving the rest for the servers in
+			// the store.
+			timeout := c.attemptTimeout(ctx, deadline, 2)
+			if protocol := c.connectAttemptServer(ctx, NodeInfo{Address: address}, timeout, log); protocol != nil {
+				return protocol, nil
+			}
+			// The hint is stale or unavailable, drop it.
+			cache.Invalidate(address)
+		}
+	}
+
 	servers, err := c.store.Get(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "get servers")
 	}
 
+	// Work on a private copy, since the store might hand out the same
+	// slice to concurrent callers and we're about to sort it in place.
+	servers = append([]NodeInfo(nil), servers...)
+
 	// Sort servers by Role, from low to high.
 	sort.Slice(servers, func(i, j int) bool {
 		return servers[i].Role < servers[j].Role
 	})
 
 	// Make an attempt for each address until we find the leader.
-	for _, server := range servers {
+	for i, server := range servers {
+		if server.Address == "" && c.config.AddressResolver != nil {
+			address, err := c.config.AddressResolver.Resolve(ctx, server.ID)
+			if err != nil {
+				log(logging.Warn, "server %d: resolve address: %v", server.ID, err)
+				continue
+			}
+			server.Address = address
+		}
 		log := func(l logging.Level, format string, a ...interface{}) {
 			format = fmt.Sprintf("server %s: ", server.Address) + format
 			log(l, format, a...)
 		}
-
-		ctx, cancel := context.WithTimeout(ctx, c.config.AttemptTimeout)
-		defer cancel()
-
-		version := VersionOne
-		protocol, leader, err := c.connectAttemptOne(ctx, server.Address, version)
-		if err == errBadProtocol {
-			log(logging.Warn, "unsupported protocol %d, attempt with legacy", version)
-			version = VersionLegacy
-			protocol, leader, err = c.connectAttemptOne(ctx, server.Address, version)
-		}
-		if err != nil {
-			// This server is unavailable, try with the next target.
-			log(logging.Warn, err.Error())
-			continue
-		}
-		if protocol != nil {
-			// We found the leader
-			log(logging.Debug, "connected")
+		timeout := c.attemptTimeout(ctx, deadline, len(servers)-i)
+		if protocol := c.connectAttemptServer(ctx, server, timeout, log); protocol != nil {
 			return protocol, nil
 		}
-		if leader == "" {
-			// This server does not know who the current leader is,
-			// try with the next target.
-			log(logging.Warn, "no known leader")
-			continue
+	}
+
+	return nil, ErrNoAvailableLeader
+}
+
+// Return the timeout for probing the next server, given the number of servers
+// left to try in this attempt.
+//
+// This is AttemptTimeout, capped to the time left before the earliest of the
+// context deadline and the retry deadline, if any. If SplitBudget is set, the
+// time left is split evenly among the servers left instead, so a single slow
+// server can't use it all up.
+func (c *Connector) attemptTimeout(ctx context.Context, deadline time.Time, left int) time.Duration {
+	var budget time.Duration
+	bounded := false
+	if d, ok := ctx.Deadline(); ok {
+		budget, bounded = time.Until(d), true
+	}
+	if !deadline.IsZero() {
+		if remaining := deadline.Sub(c.config.Clock.Now()); !bounded || remaining < budget {
+			budget, bounded = remaining, true
 		}
+	}
+
+	timeout := c.config.AttemptTimeout
+	if !bounded {
+		return timeout
+	}
+	if !c.config.SplitBudget {
+		left = 1
+	}
+	if share := budget / time.Duration(left); share < timeout {
+		timeout = share
+	}
+	return timeout
+}
 
-		// If we get here, it means this server reported that another
-		// server is the leader, let's close the connection to this
-		// server and try with the suggested one.
-		log(logging.Debug, "connect to reported leader %s", leader)
+// Whether the given retry deadline, if any, has passed according to the
+// configured clock.
+func (c *Connector) expired(deadline time.Time) bool {
+	return !deadline.IsZero() && !c.config.Clock.Now().Before(deadline)
+}
 
-		ctx, cancel = context.WithTimeout(ctx, c.config.AttemptTimeout)
-		defer cancel()
+// Try to connect to the leader through the given server, following its
+// redirect if it reports that another server is the leader. Return nil if no
+// leader could be reached this way within the given timeout.
+func (c *Connector) connectAttemptServer(ctx context.Context, server NodeInfo, timeout time.Duration, log logging.Func) *Protocol {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-		protocol, leader, err = c.connectAttemptOne(ctx, leader, version)
-		if err != nil {
-			// The leader reported by the previous server is
-			// unavailable, try with the next target.
-			log(logging.Warn, "reported leader unavailable err=%v", err)
-			continue
-		}
-		if protocol == nil {
-			// The leader reported by the target server does not consider itself
-			// the leader, try with the next target.
-			log(logging.Warn, "reported leader server is not the leader")
-			continue
-		}
+	ctx = WithTarget(ctx, Target{ID: server.ID, Address: server.Address})
+
+	version := VersionOne
+	protocol, leader, err := c.connectAttemptOne(ctx, server.Address, version)
+	if err == errBadProtocol {
+		log(logging.Warn, "unsupported protocol %d, attempt with legacy", version)
+		version = VersionLegacy
+		protocol, leader, err = c.connectAttemptOne(ctx, server.Address, version)
+	}
+	if err != nil {
+		// This server is unavailable, try with the next target.
+		log(logging.Warn, err.Error())
+		return nil
+	}
+	if protocol != nil {
+		// We found the leader
 		log(logging.Debug, "connected")
-		return protocol, nil
+		c.cacheLeader(protocol, server.Address)
+		return protocol
+	}
+	if leader == "" {
+		// This server does not know who the current leader is,
+		// try with the next target.
+		log(logging.Warn, "no known leader")
+		return nil
 	}
 
-	return nil, ErrNoAvailableLeader
+	// If we get here, it means this server reported that another
+	// server is the leader, let's close the connection to this
+	// server and try with the suggested one.
+	log(logging.Debug, "connect to reported leader %s", leader)
+
+	ctx, cancel = context.WithTimeout(ctx, c.config.AttemptTimeout)
+	defer cancel()
+
+	ctx = WithTarget(ctx, Target{Address: leader})
+
+	protocol, _, err = c.connectAttemptOne(ctx, leader, version)
+	if err != nil {
+		// The leader reported by the previous server is
+		// unavailable, try with the next target.
+		log(logging.Warn, "reported leader unavailable err=%v", err)
+		return nil
+	}
+	if protocol == nil {
+		// The leader reported by the target server does not consider itself
+		// the leader, try with the next target.
+		log(logging.Warn, "reported leader server is not the leader")
+		return nil
+	}
+	log(logging.Debug, "connected")
+	c.cacheLeader(protocol, leader)
+	return protocol
+}
+
+// Remember the given leader address, if a LeaderCache is configured, and
+// make the protocol connected to it clear the address again as soon as the
+// node replies that it's not the leader anymore.
+func (c *Connector) cacheLeader(protocol *Protocol, address string) {
+	if c.config.LeaderCache != nil {
+		c.config.LeaderCache.Set(address)
+		protocol.cache = c.config.LeaderCache
+		protocol.address = address
+	}
 }
 
 // Perform the initial handshake using the given protocol version.
@@ -209,7 +330,12 @@ func Handshake(ctx context.Context, conn net.Conn, version uint64) (*Protocol, e
 		return nil, errors.Wrap(io.ErrShortWrite, "short handshake write")
 	}
 
-	return newProtocol(version, conn), nil
+	p := newProtocol(version, conn)
+	if target, ok := TargetFromContext(ctx); ok {
+		p.target = &target
+	}
+
+	return p, nil
 }
 
 // Connect to the given dqlite server and check if it's the leader.
@@ -230,11 +356,17 @@ func (c *Connector) connectAttemptOne(ctx context.Context, address string, versi
 		return nil, "", errors.Wrap(err, "dial")
 	}
 
+	if err := c.setSocketOptions(conn); err != nil {
+		conn.Close()
+		return nil, "", err
+	}
+
 	protocol, err := Handshake(ctx, conn, version)
 	if err != nil {
 		conn.Close()
 		return nil, "", err
 	}
+	protocol.SetWireLog(c.config.WireLog)
 
 	// Send the initial Leader request.
 	request := Message{}
@@ -256,11 +388,19 @@ func (c *Connector) connectAttemptOne(ctx context.Context, address string, versi
 		return nil, "", err
 	}
 
-	_, leader, err := DecodeNodeCompat(protocol, &response)
+	id, leader, err := DecodeNodeCompat(protocol, &response)
 	if err != nil {
 		protocol.Close()
 		return nil, "", err
 	}
+	if leader == "" && id != 0 && c.config.AddressResolver != nil {
+		// The leader is known only by its ID.
+		leader, err = c.config.AddressResolver.Resolve(ctx, id)
+		if err != nil {
+			protocol.Close()
+			return nil, "", errors.Wrapf(err, "resolve address of leader %d", id)
+		}
+	}
 
 	switch leader {
 	case "":
@@ -297,9 +437,47 @@ func (c *Connector) connectAttemptOne(ctx context.Context, address string, versi
 	}
 }
 
+// Apply the configured socket options, if conn is a TCP connection.
+//
+// Nagle's algorithm is disabled unless DisableNoDelay is set. This is
+// already the default for connections created with the net package, but
+// custom dial functions might not follow it. Connections wrapping a TCP
+// connection can opt in by implementing SetNoDelay.
+func (c *Connector) setSocketOptions(conn net.Conn) error {
+	if nd, ok := conn.(noDelayer); ok {
+		if err := nd.SetNoDelay(!c.config.DisableNoDelay); err != nil {
+			return errors.Wrap(err, "set TCP_NODELAY")
+		}
+	}
+
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if c.config.ReadBufferSize != 0 {
+		if err := tcp.SetReadBuffer(c.config.ReadBufferSize); err != nil {
+			return errors.Wrap(err, "set read buffer size")
+		}
+	}
+	if c.config.WriteBufferSize != 0 {
+		if err := tcp.SetWriteBuffer(c.config.WriteBufferSize); err != nil {
+			return errors.Wrap(err, "set write buffer size")
+		}
+	}
+	return nil
+}
+
+// Implemented by connections that support turning Nagle's algorithm off,
+// such as *net.TCPConn.
+type noDelayer interface {
+	SetNoDelay(noDelay bool) error
+}
+
 // Return a retry strategy with exponential backoff, capped at the given amount
-// of time and possibly with a maximum number of retries.
-func makeRetryStrategies(factor, cap time.Duration, limit uint) []strategy.Strategy {
+// of time and possibly with a maximum number of retries. Backoff sleeps use the
+// given clock, never go past the given deadline, if any, and are cut short if
+// the given context is done.
+func makeRetryStrategies(ctx context.Context, clock Clock, deadline time.Time, factor, cap time.Duration, limit uint) []strategy.Strategy {
 	limit += 1 // Fix for change in behavior: https://github.com/Rican7/retry/pull/12
 	backoff := backoff.BinaryExponential(factor)
 
@@ -317,7 +495,22 @@ func makeRetryStrategies(factor, cap time.Duration, limit uint) []strategy.Strat
 				if duration > cap || duration <= 0 {
 					duration = cap
 				}
-				time.Sleep(duration)
+				if !deadline.IsZero() {
+					if remaining := deadline.Sub(clock.Now()); remaining < duration {
+						duration = remaining
+					}
+				}
+				if duration <= 0 {
+					// The retry deadline has passed, the next
+					// attempt will notice it.
+					return true
+				}
+				// Stop sleeping early if the context is done, the
+				// next attempt will notice it.
+				select {
+				case <-clock.After(duration):
+				case <-ctx.Done():
+				}
 			}
 
 			return true
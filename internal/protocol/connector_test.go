@@ -2,10 +2,13 @@ package protocol_test
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -39,6 +42,296 @@ func TestConnector_Success(t *testing.T) {
 	})
 }
 
+// A correct leader hint is used directly, without consulting the store.
+func TestConnector_LeaderHint(t *testing.T) {
+	address, cleanup := newNode(t, 0)
+	defer cleanup()
+
+	store := newStore(t, []string{"@test-123"})
+	cache := protocol.NewLeaderCache()
+	cache.Set(address)
+
+	log, check := newLogFunc(t)
+	connector := protocol.NewConnector(0, store, protocol.Config{LeaderCache: cache}, log)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client, err := connector.Connect(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, client.Close())
+
+	check([]string{
+		"DEBUG: attempt 1: leader hint @test-0: connected",
+	})
+	assert.Equal(t, address, cache.Get())
+}
+
+// A stale leader hint is followed by one redirect to the actual leader,
+// which replaces the hint.
+func TestConnector_LeaderHintStale(t *testing.T) {
+	address, cleanup := newNode(t, 0)
+	defer cleanup()
+
+	follower := newFakeFollower(t, "@test-follower", address)
+	defer follower.Close()
+
+	store := newStore(t, []string{"@test-123"})
+	cache := protocol.NewLeaderCache()
+	cache.Set("@test-follower")
+
+	log, check := newLogFunc(t)
+	connector := protocol.NewConnector(0, store, protocol.Config{LeaderCache: cache}, log)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client, err := connector.Connect(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, client.Close())
+
+	check([]string{
+		"DEBUG: attempt 1: leader hint @test-follower: connect to reported leader @test-0",
+		"DEBUG: attempt 1: leader hint @test-follower: connected",
+	})
+	assert.Equal(t, address, cache.Get())
+}
+
+// A cached leader that replies it's not the leader anymore is dropped from
+// the cache.
+func TestConnector_LeaderHintNotLeader(t *testing.T) {
+	listener, err := net.Listen("unix", "@t-down")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Handshake and Leader request, reply that we're the leader.
+		if _, err := io.ReadFull(conn, make([]byte, 8+16)); err != nil {
+			return
+		}
+		response := make([]byte, 24)
+		binary.LittleEndian.PutUint32(response[0:], 2)
+		response[4] = protocol.ResponseNode
+		binary.LittleEndian.PutUint64(response[8:], 1)
+		copy(response[16:], "@t-down")
+		conn.Write(response)
+
+		readRequest(conn) // Client request
+		response = make([]byte, 16)
+		binary.LittleEndian.PutUint32(response[0:], 1)
+		response[4] = protocol.ResponseWelcome
+		conn.Write(response)
+
+		readRequest(conn) // Exec request
+		conn.Write(newFailureResponse(10|40<<8, "not leader"))
+
+		readRequest(conn) // Leader request, no known leader
+		response = make([]byte, 24)
+		binary.LittleEndian.PutUint32(response[0:], 2)
+		response[4] = protocol.ResponseNode
+		conn.Write(response)
+	}()
+
+	store := newStore(t, []string{"@test-123"})
+	cache := protocol.NewLeaderCache()
+	cache.Set("@t-down")
+
+	connector := protocol.NewConnector(0, store, protocol.Config{LeaderCache: cache}, logging.Test(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client, err := connector.Connect(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "@t-down", cache.Get())
+
+	request, response := newMessagePair(512, 512)
+	protocol.EncodeExecSQLV0(&request, 0, "INSERT INTO test VALUES (1)", nil)
+
+	err = client.Call(ctx, &request, &response)
+	require.IsType(t, &protocol.ErrNotLeader{}, err)
+	assert.Equal(t, "", cache.Get())
+}
+
+// Store entries without an address are resolved by ID.
+func TestConnector_AddressResolver(t *testing.T) {
+	address, cleanup := newNode(t, 0)
+	defer cleanup()
+
+	store := protocol.NewInmemNodeStore()
+	require.NoError(t, store.Set(context.Background(), []protocol.NodeInfo{{ID: 1}}))
+
+	resolver := mapResolver{1: address}
+
+	log, check := newLogFunc(t)
+	connector := protocol.NewConnector(0, store, protocol.Config{AddressResolver: resolver}, log)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client, err := connector.Connect(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, client.Close())
+
+	check([]string{
+		"DEBUG: attempt 1: server @test-0: connected",
+	})
+}
+
+// A redirect reporting only the leader ID is resolved too.
+func TestConnector_AddressResolverRedirect(t *testing.T) {
+	address, cleanup := newNode(t, 0)
+	defer cleanup()
+
+	follower := newFakeFollower(t, "@test-follower", "")
+	defer follower.Close()
+
+	store := newStore(t, []string{"@test-follower"})
+	resolver := mapResolver{1: address}
+
+	log, check := newLogFunc(t)
+	connector := protocol.NewConnector(0, store, protocol.Config{AddressResolver: resolver}, log)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client, err := connector.Connect(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, client.Close())
+
+	check([]string{
+		"DEBUG: attempt 1: server @test-follower: connect to reported leader @test-0",
+		"DEBUG: attempt 1: server @test-follower: connected",
+	})
+}
+
+// Store entries whose address can't be resolved are skipped.
+func TestConnector_AddressResolverError(t *testing.T) {
+	store := protocol.NewInmemNodeStore()
+	require.NoError(t, store.Set(context.Background(), []protocol.NodeInfo{{ID: 1}}))
+
+	config := protocol.Config{AddressResolver: mapResolver{}, RetryLimit: 1}
+	log, check := newLogFunc(t)
+	connector := protocol.NewConnector(0, store, config, log)
+
+	_, err := connector.Connect(context.Background())
+	assert.Equal(t, protocol.ErrNoAvailableLeader, err)
+
+	check([]string{
+		"WARN: attempt 1: server 1: resolve address: unknown node 1",
+		"WARN: attempt 2: server 1: resolve address: unknown node 1",
+	})
+}
+
+// Nagle's algorithm is turned off on dialed connections, unless disabled.
+func TestConnector_NoDelay(t *testing.T) {
+	cases := []struct {
+		disable bool
+		calls   []bool
+	}{
+		{false, []bool{true}},
+		{true, []bool{false}},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("disable=%v", c.disable), func(t *testing.T) {
+			conn := &noDelayConn{}
+			dial := func(context.Context, string) (net.Conn, error) {
+				client, server := net.Pipe()
+				server.Close()
+				conn.Conn = client
+				return conn, nil
+			}
+
+			store := newStore(t, []string{"@test-0"})
+			config := protocol.Config{Dial: dial, DisableNoDelay: c.disable}
+			connector := protocol.NewConnector(0, store, config, logging.Test(t))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := connector.Connect(ctx)
+			require.Error(t, err)
+			assert.Equal(t, c.calls, conn.calls[:1])
+		})
+	}
+}
+
+// Connection recording the calls to SetNoDelay.
+type noDelayConn struct {
+	net.Conn
+	calls []bool
+}
+
+func (c *noDelayConn) SetNoDelay(noDelay bool) error {
+	c.calls = append(c.calls, noDelay)
+	return nil
+}
+
+// A single connector can be used by many goroutines at the same time.
+func TestConnector_ConcurrentConnect(t *testing.T) {
+	address, cleanup := newNode(t, 0)
+	defer cleanup()
+
+	store := newStore(t, []string{address})
+	connector := protocol.NewConnector(0, store, protocol.Config{}, logging.Test(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	n := 32
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			client, err := connector.Connect(ctx)
+			if err == nil {
+				err = client.Close()
+			}
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		assert.NoError(t, <-errs)
+	}
+}
+
+// Concurrent calls don't step on each other even if the store returns the
+// same underlying slice every time.
+func TestConnector_ConcurrentConnectSharedStore(t *testing.T) {
+	store := &sharedNodeStore{servers: []protocol.NodeInfo{
+		{ID: 1, Address: "@test-123", Role: protocol.Spare},
+		{ID: 2, Address: "@test-456", Role: protocol.Voter},
+	}}
+	config := protocol.Config{
+		BackoffFactor: time.Millisecond,
+		RetryLimit:    2,
+	}
+	connector := protocol.NewConnector(0, store, config, logging.Test(t))
+
+	n := 16
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := connector.Connect(context.Background())
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		assert.Equal(t, protocol.ErrNoAvailableLeader, <-errs)
+	}
+
+	assert.Equal(t, protocol.Spare, store.servers[0].Role)
+}
+
 // The network connection can't be established within the specified number of
 // attempts.
 func TestConnector_LimitRetries(t *testing.T) {
@@ -59,6 +352,55 @@ func TestConnector_LimitRetries(t *testing.T) {
 	})
 }
 
+// Backoff intervals between retries are driven by the configured clock.
+func TestConnector_Clock(t *testing.T) {
+	store := newStore(t, []string{"@test-123"})
+	clock := &fakeClock{}
+	config := protocol.Config{
+		BackoffFactor: 100 * time.Millisecond,
+		BackoffCap:    time.Second,
+		RetryLimit:    4,
+		Clock:         clock,
+	}
+	log := func(logging.Level, string, ...interface{}) {}
+	connector := protocol.NewConnector(0, store, config, log)
+
+	_, err := connector.Connect(context.Background())
+	assert.Equal(t, protocol.ErrNoAvailableLeader, err)
+
+	assert.Equal(t, []time.Duration{
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	}, clock.waits)
+}
+
+// The network connection can't be established within the maximum retry
+// duration, even if the number of retries is unlimited.
+func TestConnector_MaxRetryDuration(t *testing.T) {
+	store := newStore(t, []string{"@test-123"})
+	clock := &fakeClock{}
+	config := protocol.Config{
+		BackoffFactor:    50 * time.Millisecond,
+		BackoffCap:       time.Second,
+		MaxRetryDuration: 250 * time.Millisecond,
+		Clock:            clock,
+	}
+	log := func(logging.Level, string, ...interface{}) {}
+	connector := protocol.NewConnector(0, store, config, log)
+
+	_, err := connector.Connect(context.Background())
+	assert.Equal(t, protocol.ErrRetryDeadlineExceeded, err)
+
+	// The second wait is cut short by the deadline, and no attempt is
+	// made after it.
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		150 * time.Millisecond,
+	}, clock.waits)
+}
+
 // The network connection can't be established because of a connection timeout.
 func TestConnector_DialTimeout(t *testing.T) {
 	store := newStore(t, []string{"8.8.8.8:9000"})
@@ -78,6 +420,42 @@ func TestConnector_DialTimeout(t *testing.T) {
 	})
 }
 
+// The node being contacted is attached to the context passed to the dial
+// function.
+func TestConnector_DialTarget(t *testing.T) {
+	store := newStore(t, []string{"@test-0", "@test-1"})
+	log, check := newLogFunc(t)
+
+	var targets []protocol.Target
+	config := protocol.Config{
+		Dial: func(ctx context.Context, address string) (net.Conn, error) {
+			target, ok := protocol.TargetFromContext(ctx)
+			require.True(t, ok)
+			targets = append(targets, target)
+			return nil, fmt.Errorf("boom")
+		},
+		RetryLimit: 1,
+	}
+	connector := protocol.NewConnector(0, store, config, log)
+
+	_, err := connector.Connect(context.Background())
+	assert.Equal(t, protocol.ErrNoAvailableLeader, err)
+
+	assert.Equal(t, []protocol.Target{
+		{ID: 0, Address: "@test-0"},
+		{ID: 1, Address: "@test-1"},
+		{ID: 0, Address: "@test-0"},
+		{ID: 1, Address: "@test-1"},
+	}, targets)
+
+	check([]string{
+		"WARN: attempt 1: server @test-0: dial: boom",
+		"WARN: attempt 1: server @test-1: dial: boom",
+		"WARN: attempt 2: server @test-0: dial: boom",
+		"WARN: attempt 2: server @test-1: dial: boom",
+	})
+}
+
 // Connection failed because the server store is empty.
 func TestConnector_EmptyNodeStore(t *testing.T) {
 	store := newStore(t, []string{})
@@ -291,6 +669,124 @@ func TestConnector_AttemptTimeout(t *testing.T) {
 // 	assert.NoError(t, client.Close())
 // }
 
+// Compare the throughput of dumping a large database over TCP with the OS
+// default socket buffer sizes and with larger ones.
+func BenchmarkConnector_DumpBufferSizes(b *testing.B) {
+	dir, dirCleanup := newDir(b)
+	defer dirCleanup()
+
+	address := "127.0.0.1:9050"
+	server, err := bindings.NewNode(context.Background(), 1, address, dir)
+	require.NoError(b, err)
+	require.NoError(b, server.SetBindAddress(address))
+	require.NoError(b, server.Start())
+	defer server.Close()
+	defer server.Stop()
+
+	store := newStore(b, []string{address})
+	log := func(logging.Level, string, ...interface{}) {}
+
+	// Create a database of about 16 megabytes.
+	p, err := protocol.NewConnector(0, store, protocol.Config{}, log).Connect(context.Background())
+	require.NoError(b, err)
+
+	request := protocol.Message{}
+	request.Init(4096)
+	response := protocol.Message{}
+	response.Init(4096)
+
+	protocol.EncodeOpen(&request, "test.db", 0, "volatile")
+	require.NoError(b, p.Call(context.Background(), &request, &response))
+	db, err := protocol.DecodeDb(&response)
+	require.NoError(b, err)
+
+	protocol.EncodeExecSQLV0(&request, uint64(db), "CREATE TABLE test (data BLOB)", nil)
+	require.NoError(b, p.Call(context.Background(), &request, &response))
+	for i := 0; i < 16; i++ {
+		protocol.EncodeExecSQLV0(&request, uint64(db), "INSERT INTO test(data) VALUES(randomblob(1048576))", nil)
+		require.NoError(b, p.Call(context.Background(), &request, &response))
+	}
+	p.Close()
+
+	cases := []struct {
+		name   string
+		config protocol.Config
+	}{
+		{"default", protocol.Config{}},
+		{"4MiB", protocol.Config{ReadBufferSize: 4 << 20, WriteBufferSize: 4 << 20}},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			p, err := protocol.NewConnector(0, store, c.config, log).Connect(context.Background())
+			require.NoError(b, err)
+			defer p.Close()
+
+			request := protocol.Message{}
+			request.Init(4096)
+			response := protocol.Message{}
+			response.Init(4096)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				protocol.EncodeDump(&request, "test.db")
+				require.NoError(b, p.Call(context.Background(), &request, &response))
+				files, err := protocol.DecodeFiles(&response)
+				require.NoError(b, err)
+				files.Close()
+			}
+		})
+	}
+}
+
+// With SplitBudget, slow servers don't consume the whole context budget, and
+// every server in the store gets probed.
+func TestConnector_SplitBudget(t *testing.T) {
+	var mu sync.Mutex
+	addresses := []string{}
+	accepted := []string{}
+	for i := 0; i < 4; i++ {
+		listener, err := net.Listen("unix", fmt.Sprintf("@test-slow-%d", i))
+		require.NoError(t, err)
+		defer listener.Close()
+
+		address := listener.Addr().String()
+
+		// Accept connections but never reply to the handshake.
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				accepted = append(accepted, address)
+				mu.Unlock()
+				go func() {
+					io.Copy(ioutil.Discard, conn)
+					conn.Close()
+				}()
+			}
+		}()
+
+		addresses = append(addresses, address)
+	}
+
+	store := newStore(t, addresses)
+	config := protocol.Config{SplitBudget: true, RetryLimit: 1}
+	connector := protocol.NewConnector(0, store, config, logging.Test(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+
+	_, err := connector.Connect(ctx)
+	assert.Equal(t, protocol.ErrNoAvailableLeader, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, addresses, accepted)
+}
+
 // Return a log function that emits messages using the test logger as well as
 // collecting them into a slice. The second function returned can be used to
 // assert that the collected messages match the given ones.
@@ -308,8 +804,83 @@ func newLogFunc(t *testing.T) (logging.Func, func([]string)) {
 	return log, check
 }
 
+// Listen on the given address and reply to the Leader request of each
+// connection with the given leader address. The address must be 7 bytes long.
+func newFakeFollower(t *testing.T, address string, leader string) net.Listener {
+	t.Helper()
+	require.True(t, len(leader) <= 7)
+
+	listener, err := net.Listen("unix", address)
+	require.NoError(t, err)
+
+	response := make([]byte, 24)
+	binary.LittleEndian.PutUint32(response[0:], 2) // Body words
+	response[4] = protocol.ResponseNode
+	binary.LittleEndian.PutUint64(response[8:], 1) // Leader ID
+	copy(response[16:], leader)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			request := make([]byte, 8+16) // Handshake and Leader request
+			if _, err := io.ReadFull(conn, request); err == nil {
+				conn.Write(response)
+			}
+			conn.Close()
+		}
+	}()
+
+	return listener
+}
+
+// AddressResolver backed by a map from node IDs to addresses.
+type mapResolver map[uint64]string
+
+func (r mapResolver) Resolve(ctx context.Context, id uint64) (string, error) {
+	address, ok := r[id]
+	if !ok {
+		return "", fmt.Errorf("unknown node %d", id)
+	}
+	return address, nil
+}
+
+// Clock that records the requested waits and lets them elapse immediately,
+// advancing its own time.
+type fakeClock struct {
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// Node store that always returns the same slice, without copying it.
+type sharedNodeStore struct {
+	servers []protocol.NodeInfo
+}
+
+func (s *sharedNodeStore) Get(context.Context) ([]protocol.NodeInfo, error) {
+	return s.servers, nil
+}
+
+func (s *sharedNodeStore) Set(context.Context, []protocol.NodeInfo) error {
+	return nil
+}
+
 // Create a new in-memory server store populated with the given addresses.
-func newStore(t *testing.T, addresses []string) protocol.NodeStore {
+func newStore(t testing.TB, addresses []string) protocol.NodeStore {
 	t.Helper()
 
 	servers := make([]protocol.NodeInfo, len(addresses))
@@ -349,7 +920,7 @@ func newNode(t *testing.T, index int) (string, func()) {
 }
 
 // Return a new temporary directory.
-func newDir(t *testing.T) (string, func()) {
+func newDir(t testing.TB) (string, func()) {
 	t.Helper()
 
 	dir, err := ioutil.TempDir("", "dqlite-connector-test-")
@@ -15,3 +15,24 @@ func Dial(ctx context.Context, address string) (net.Conn, error) {
 	dialer := net.Dialer{}
 	return dialer.DialContext(ctx, family, address)
 }
+
+// TCPDialFromAddr returns a dial function for TCP endpoints which binds the
+// local end of each connection to the given address. The address can be
+// either a bare IP or an IP with a port.
+func TCPDialFromAddr(localAddr string) DialFunc {
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		local, err := resolveLocalAddr(localAddr)
+		if err != nil {
+			return nil, err
+		}
+		dialer := net.Dialer{LocalAddr: local}
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+}
+
+func resolveLocalAddr(address string) (*net.TCPAddr, error) {
+	if ip := net.ParseIP(strings.Trim(address, "[]")); ip != nil {
+		return &net.TCPAddr{IP: ip}, nil
+	}
+	return net.ResolveTCPAddr("tcp", address)
+}
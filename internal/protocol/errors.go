@@ -6,12 +6,13 @@ import (
 
 // Client errors.
 var (
-	ErrNoAvailableLeader = fmt.Errorf("no available dqlite leader server found")
-	errStop              = fmt.Errorf("connector was stopped")
-	errStaleLeader       = fmt.Errorf("server has lost leadership")
-	errNotClustered      = fmt.Errorf("server is not clustered")
-	errNegativeRead      = fmt.Errorf("reader returned negative count from Read")
-	errMessageEOF        = fmt.Errorf("message eof")
+	ErrNoAvailableLeader     = fmt.Errorf("no available dqlite leader server found")
+	ErrRetryDeadlineExceeded = fmt.Errorf("no dqlite leader server found within the maximum retry duration")
+	errStop                  = fmt.Errorf("connector was stopped")
+	errStaleLeader           = fmt.Errorf("server has lost leadership")
+	errNotClustered          = fmt.Errorf("server is not clustered")
+	errNegativeRead          = fmt.Errorf("reader returned negative count from Read")
+	errMessageEOF            = fmt.Errorf("message eof")
 )
 
 // ErrRequest is returned in case of request failure.
@@ -24,6 +25,50 @@ func (e ErrRequest) Error() string {
 	return fmt.Sprintf("%s (%d)", e.Description, e.Code)
 }
 
+// ErrNotLeader is returned by Protocol.Call when the server fails a request
+// because it's not the leader.
+//
+// Leader holds the leader known by the server at the time of the failure, or
+// nil if it doesn't know any.
+type ErrNotLeader struct {
+	Code        uint64
+	Description string
+	Leader      *NodeInfo
+}
+
+func (e *ErrNotLeader) Error() string {
+	if e.Leader == nil {
+		return fmt.Sprintf("%s (%d)", e.Description, e.Code)
+	}
+	return fmt.Sprintf("%s (%d), leader is %s", e.Description, e.Code, e.Leader.Address)
+}
+
+// SQLite extended error codes returned by servers that are not the leader.
+const (
+	errIoErrNotLeader       = 10 | 40<<8
+	errIoErrNotLeaderLegacy = 10 | 32<<8
+)
+
+// ErrPartialWrite is returned when a request message could be written only
+// partially to the connection.
+//
+// The server never received the full request, so it's safe to retry it, but
+// the connection is left in an unknown state and won't be used anymore.
+type ErrPartialWrite struct {
+	Written int   // Number of bytes of the message that were written.
+	Size    int   // Total size of the message.
+	Err     error // Error that interrupted the write.
+}
+
+func (e *ErrPartialWrite) Error() string {
+	return fmt.Sprintf("partial write (%d of %d bytes): %v", e.Written, e.Size, e.Err)
+}
+
+// Unwrap returns the error that interrupted the write.
+func (e *ErrPartialWrite) Unwrap() error {
+	return e.Err
+}
+
 // ErrRowsPart is returned when the first batch of a multi-response result
 // batch is done.
 var ErrRowsPart = fmt.Errorf("not all rows were returned in this response")
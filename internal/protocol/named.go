@@ -0,0 +1,130 @@
+package protocol
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// BindNamedValues maps the given values to the parameters of the given SQL
+// text, returning them in the order expected by the wire protocol.
+//
+// If none of the values has a name, they are returned unchanged and bound by
+// position. Otherwise every value must have a name, which is matched against
+// the :name, @name and $name parameters of the statement, and the statement
+// must not contain positional parameters (? or ?NNN).
+//
+// Like SQLite, parameters are numbered in order of first appearance, and
+// occurrences of the same name share the same value.
+func BindNamedValues(sql string, values NamedValues) (NamedValues, error) {
+	named := 0
+	for _, value := range values {
+		if value.Name != "" {
+			named++
+		}
+	}
+	if named == 0 {
+		return values, nil
+	}
+	if named != len(values) {
+		return nil, fmt.Errorf("can't mix named and positional arguments")
+	}
+
+	params, positional := scanParameters(sql)
+	if positional {
+		return nil, fmt.Errorf("can't bind named arguments to positional parameters")
+	}
+
+	byName := make(map[string]driver.NamedValue, len(values))
+	for _, value := range values {
+		if _, ok := byName[value.Name]; ok {
+			return nil, fmt.Errorf("duplicate argument %q", value.Name)
+		}
+		byName[value.Name] = value
+	}
+
+	bound := make(NamedValues, len(params))
+	for i, param := range params {
+		value, ok := byName[param]
+		if !ok {
+			return nil, fmt.Errorf("missing argument for parameter %q", param)
+		}
+		delete(byName, param)
+		bound[i] = driver.NamedValue{Ordinal: i + 1, Value: value.Value}
+	}
+	for _, value := range values {
+		if _, ok := byName[value.Name]; ok {
+			return nil, fmt.Errorf("no parameter named %q", value.Name)
+		}
+	}
+
+	return bound, nil
+}
+
+// Return the names of the named parameters found in the given SQL text, in
+// order of first appearance and without their prefix, and whether any
+// positional parameter was found.
+//
+// String literals, quoted identifiers and comments are skipped.
+func scanParameters(sql string) ([]string, bool) {
+	names := []string{}
+	seen := map[string]bool{}
+	positional := false
+
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; c {
+		case '\'', '"', '`':
+			i = skipUntil(sql, i+1, string(c))
+		case '[':
+			i = skipUntil(sql, i+1, "]")
+		case '-':
+			if i+1 < len(sql) && sql[i+1] == '-' {
+				i = skipUntil(sql, i+2, "\n")
+			}
+		case '/':
+			if i+1 < len(sql) && sql[i+1] == '*' {
+				i = skipUntil(sql, i+2, "*/")
+			}
+		case '?':
+			positional = true
+			for i+1 < len(sql) && isDigit(sql[i+1]) {
+				i++
+			}
+		case ':', '@', '$':
+			j := i + 1
+			for j < len(sql) && isIdentifierChar(sql[j]) {
+				j++
+			}
+			if j == i+1 {
+				continue
+			}
+			name := sql[i+1 : j]
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			i = j - 1
+		}
+	}
+
+	return names, positional
+}
+
+// Return the index of the last byte of the first occurrence of end in sql,
+// starting at the given offset, or the index of the last byte of sql if not
+// found.
+func skipUntil(sql string, offset int, end string) int {
+	for i := offset; i+len(end) <= len(sql); i++ {
+		if sql[i:i+len(end)] == end {
+			return i + len(end) - 1
+		}
+	}
+	return len(sql) - 1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierChar(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
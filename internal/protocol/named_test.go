@@ -0,0 +1,91 @@
+package protocol_test
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/canonical/go-dqlite/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindNamedValues(t *testing.T) {
+	sql := "SELECT * FROM test WHERE a = :a AND b = @b AND c = $c AND d = :a"
+	values := []driver.NamedValue{
+		{Name: "c", Ordinal: 1, Value: int64(3)},
+		{Name: "a", Ordinal: 2, Value: int64(1)},
+		{Name: "b", Ordinal: 3, Value: "two"},
+	}
+
+	bound, err := protocol.BindNamedValues(sql, values)
+	require.NoError(t, err)
+	assert.Equal(t, []driver.NamedValue{
+		{Ordinal: 1, Value: int64(1)},
+		{Ordinal: 2, Value: "two"},
+		{Ordinal: 3, Value: int64(3)},
+	}, bound)
+}
+
+// Text that looks like a parameter inside literals, quoted identifiers and
+// comments is ignored.
+func TestBindNamedValues_SkipQuoted(t *testing.T) {
+	sql := `SELECT ':x', "@y", [$z], ` + "`:w`" + ` -- :v ?
+FROM test /* @u ? */ WHERE a = :a`
+	values := []driver.NamedValue{{Name: "a", Ordinal: 1, Value: int64(1)}}
+
+	bound, err := protocol.BindNamedValues(sql, values)
+	require.NoError(t, err)
+	assert.Equal(t, []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}, bound)
+}
+
+func TestBindNamedValues_Positional(t *testing.T) {
+	values := []driver.NamedValue{
+		{Ordinal: 1, Value: int64(1)},
+		{Ordinal: 2, Value: int64(2)},
+	}
+
+	bound, err := protocol.BindNamedValues("SELECT ?, ?", values)
+	require.NoError(t, err)
+	assert.Equal(t, values, bound)
+}
+
+func TestBindNamedValues_Error(t *testing.T) {
+	cases := []struct {
+		sql    string
+		values []driver.NamedValue
+		err    string
+	}{
+		{
+			"SELECT :a, ?",
+			[]driver.NamedValue{{Name: "a", Ordinal: 1}, {Ordinal: 2}},
+			"can't mix named and positional arguments",
+		},
+		{
+			"SELECT :a, ?2",
+			[]driver.NamedValue{{Name: "a", Ordinal: 1}},
+			"can't bind named arguments to positional parameters",
+		},
+		{
+			"SELECT :a, :b",
+			[]driver.NamedValue{{Name: "a", Ordinal: 1}},
+			`missing argument for parameter "b"`,
+		},
+		{
+			"SELECT :a",
+			[]driver.NamedValue{{Name: "a", Ordinal: 1}, {Name: "b", Ordinal: 2}},
+			`no parameter named "b"`,
+		},
+		{
+			"SELECT :a",
+			[]driver.NamedValue{{Name: "a", Ordinal: 1}, {Name: "a", Ordinal: 2}},
+			`duplicate argument "a"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.sql, func(t *testing.T) {
+			_, err := protocol.BindNamedValues(c.sql, c.values)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
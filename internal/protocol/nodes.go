@@ -0,0 +1,39 @@
+package protocol
+
+import "fmt"
+
+// DecodeNodesFunc decodes a Nodes response like DecodeNodes, but invokes the
+// given function with each node as soon as it's decoded, instead of collecting
+// all of them into a slice.
+//
+// If the function returns an error, decoding stops and the error is returned.
+func DecodeNodesFunc(response *Message, f func(NodeInfo) error) error {
+	mtype, _ := response.getHeader()
+
+	if mtype == ResponseFailure {
+		e := ErrRequest{}
+		e.Code = response.getUint64()
+		e.Description = response.getString()
+		return e
+	}
+
+	if mtype != ResponseNodes {
+		return fmt.Errorf("decode %s: unexpected type %d", responseDesc(ResponseNodes), mtype)
+	}
+
+	n := response.getUint64()
+	for i := uint64(0); i < n; i++ {
+		if response.hasBeenConsumed() {
+			return fmt.Errorf("decode %s: truncated after %d of %d nodes", responseDesc(ResponseNodes), i, n)
+		}
+		node := NodeInfo{}
+		node.ID = response.getUint64()
+		node.Address = response.getString()
+		node.Role = NodeRole(response.getUint64())
+		if err := f(node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
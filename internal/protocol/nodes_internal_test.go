@@ -0,0 +1,68 @@
+package protocol
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Nodes are handed to the callback one at a time, as they get decoded.
+func TestDecodeNodesFunc(t *testing.T) {
+	n := 1000
+	message := newNodesMessage(n, n)
+
+	ids := []uint64{}
+	err := DecodeNodesFunc(&message, func(node NodeInfo) error {
+		ids = append(ids, node.ID)
+		assert.Equal(t, fmt.Sprintf("10.0.0.%d:9000", node.ID), node.Address)
+		assert.Equal(t, Voter, node.Role)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, ids, n)
+	assert.True(t, message.hasBeenConsumed())
+}
+
+// Decoding stops as soon as the callback fails, without reading further.
+func TestDecodeNodesFunc_Stop(t *testing.T) {
+	message := newNodesMessage(1000, 1000)
+
+	calls := 0
+	err := DecodeNodesFunc(&message, func(node NodeInfo) error {
+		calls++
+		if calls == 10 {
+			return fmt.Errorf("enough")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "enough")
+	assert.Equal(t, 10, calls)
+	assert.False(t, message.hasBeenConsumed())
+}
+
+func TestDecodeNodesFunc_Truncated(t *testing.T) {
+	message := newNodesMessage(3, 2)
+
+	err := DecodeNodesFunc(&message, func(NodeInfo) error { return nil })
+	assert.EqualError(t, err, "decode nodes: truncated after 2 of 3 nodes")
+}
+
+// Forge a Nodes response announcing the given number of nodes, but containing
+// only the given number of entries.
+func newNodesMessage(announced, entries int) Message {
+	message := Message{}
+	message.Init(4096)
+
+	message.putUint64(uint64(announced))
+	for i := 0; i < entries; i++ {
+		message.putUint64(uint64(i + 1))
+		message.putString(fmt.Sprintf("10.0.0.%d:9000", i+1))
+		message.putUint64(uint64(Voter))
+	}
+	message.putHeader(ResponseNodes, 0)
+	message.Rewind()
+
+	return message
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/canonical/go-dqlite/logging"
 	"github.com/pkg/errors"
 )
 
@@ -18,6 +19,10 @@ type Protocol struct {
 	closeCh chan struct{} // Stops the heartbeat when the connection gets closed
 	mu      sync.Mutex    // Serialize requests
 	netErr  error         // A network error occurred
+	target  *Target       // Node we're connected to, if known.
+	wireLog logging.Func  // Logs the type and size of every message, if set.
+	cache   *LeaderCache  // Cache holding this node's address, if any.
+	address string        // Address of the node, as stored in the cache.
 }
 
 func newProtocol(version uint64, conn net.Conn) *Protocol {
@@ -42,12 +47,10 @@ func (p *Protocol) Call(ctx context.Context, request, response *Message) (err er
 		return p.netErr
 	}
 
+	// Any failure while sending or receiving leaves the stream in an
+	// unknown state, so the connection can't be reused.
 	defer func() {
-		if err == nil {
-			return
-		}
-		switch errors.Cause(err).(type) {
-		case *net.OpError:
+		if _, ok := err.(*ErrNotLeader); err != nil && !ok {
 			p.netErr = err
 		}
 	}()
@@ -64,16 +67,94 @@ func (p *Protocol) Call(ctx context.Context, request, response *Message) (err er
 	desc := requestDesc(request.mtype)
 
 	if err = p.send(request); err != nil {
-		return errors.Wrapf(err, "call %s (budget %s): send", desc, budget)
+		return p.targetError(errors.Wrapf(err, "call %s (budget %s): send", desc, budget))
 	}
 
 	if err = p.recv(response); err != nil {
-		return errors.Wrapf(err, "call %s (budget %s): receive", desc, budget)
+		return p.targetError(errors.Wrapf(err, "call %s (budget %s): receive", desc, budget))
+	}
+
+	if e := p.notLeader(response); e != nil {
+		return e
 	}
 
 	return
 }
 
+// If the given response is a failure caused by the server not being the
+// leader, return an ErrNotLeader holding the leader reported by the server.
+// Otherwise leave the response untouched and return nil.
+func (p *Protocol) notLeader(response *Message) *ErrNotLeader {
+	if response.mtype != ResponseFailure {
+		return nil
+	}
+
+	offset := response.body.Offset
+	code := response.getUint64()
+	description := response.getString()
+	response.body.Offset = offset
+
+	if code != errIoErrNotLeader && code != errIoErrNotLeaderLegacy {
+		return nil
+	}
+
+	e := &ErrNotLeader{Code: code, Description: description}
+
+	// The node we're connected to is not the leader anymore, so it
+	// shouldn't be tried first on the next connection.
+	if p.cache != nil {
+		p.cache.Invalidate(p.address)
+	}
+
+	// Ask the same server who the leader is.
+	request := Message{}
+	request.Init(16)
+	leader := Message{}
+	leader.Init(512)
+
+	EncodeLeader(&request)
+
+	if err := p.send(&request); err != nil {
+		p.netErr = err
+		return e
+	}
+	if err := p.recv(&leader); err != nil {
+		p.netErr = err
+		return e
+	}
+
+	id, address, err := DecodeNodeCompat(p, &leader)
+	if err == nil && address != "" {
+		e.Leader = &NodeInfo{ID: id, Address: address}
+	}
+
+	return e
+}
+
+// SetWireLog makes the protocol log the type and size of every message sent
+// and received, at debug level. Message contents are never logged.
+//
+// It must be called before the protocol is used.
+func (p *Protocol) SetWireLog(log logging.Func) {
+	p.wireLog = log
+}
+
+// Target returns the node this protocol is connected to, if known.
+func (p *Protocol) Target() (Target, bool) {
+	if p.target == nil {
+		return Target{}, false
+	}
+	return *p.target, true
+}
+
+// Wrap the given error with the target node, if known.
+func (p *Protocol) targetError(err error) error {
+	if p.target == nil {
+		return err
+	}
+	return &ErrTarget{Target: *p.target, Err: err}
+}
+
 // More is used when a request maps to multiple responses.
 func (p *Protocol) More(ctx context.Context, response *Message) error {
 	return p.recv(response)
@@ -121,42 +202,59 @@ func (p *Protocol) Close() error {
 }
 
 func (p *Protocol) send(req *Message) error {
-	if err := p.sendHeader(req); err != nil {
-		return errors.Wrap(err, "header")
+	size := messageHeaderSize + req.body.Offset
+
+	n, err := p.sendHeader(req)
+	if err != nil {
+		return partialWrite(n, size, errors.Wrap(err, "header"))
 	}
 
-	if err := p.sendBody(req); err != nil {
-		return errors.Wrap(err, "body")
+	m, err := p.sendBody(req)
+	if err != nil {
+		return partialWrite(n+m, size, errors.Wrap(err, "body"))
+	}
+
+	if p.wireLog != nil {
+		p.wireLog(logging.Debug, "wire: send %s request (%d bytes)", requestDesc(req.mtype), size)
 	}
 
 	return nil
 }
 
-func (p *Protocol) sendHeader(req *Message) error {
+// Return an ErrPartialWrite if some bytes of the message were written,
+// otherwise return the given error as is.
+func partialWrite(written, size int, err error) error {
+	if written == 0 {
+		return err
+	}
+	return &ErrPartialWrite{Written: written, Size: size, Err: err}
+}
+
+func (p *Protocol) sendHeader(req *Message) (int, error) {
 	n, err := p.conn.Write(req.header[:])
 	if err != nil {
-		return err
+		return n, err
 	}
 
 	if n != messageHeaderSize {
-		return io.ErrShortWrite
+		return n, io.ErrShortWrite
 	}
 
-	return nil
+	return n, nil
 }
 
-func (p *Protocol) sendBody(req *Message) error {
+func (p *Protocol) sendBody(req *Message) (int, error) {
 	buf := req.body.Bytes[:req.body.Offset]
 	n, err := p.conn.Write(buf)
 	if err != nil {
-		return err
+		return n, err
 	}
 
 	if n != len(buf) {
-		return io.ErrShortWrite
+		return n, io.ErrShortWrite
 	}
 
-	return nil
+	return n, nil
 }
 
 func (p *Protocol) recv(res *Message) error {
@@ -170,6 +268,11 @@ func (p *Protocol) recv(res *Message) error {
 		return errors.Wrap(err, "body")
 	}
 
+	if p.wireLog != nil {
+		size := messageHeaderSize + int(res.words*messageWordSize)
+		p.wireLog(logging.Debug, "wire: recv %s response (%d bytes)", responseDesc(res.mtype), size)
+	}
+
 	return nil
 }
 
@@ -2,11 +2,17 @@ package protocol_test
 
 import (
 	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/canonical/go-dqlite/internal/protocol"
 	"github.com/canonical/go-dqlite/logging"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -56,6 +62,213 @@ CREATE TABLE baz (n INT);
 	makeCall(t, p, &request, &response)
 }
 
+// Errors returned by Call include the target node found in the context passed
+// to Handshake.
+func TestProtocol_CallErrorTarget(t *testing.T) {
+	conn, server := net.Pipe()
+	go func() {
+		buf := make([]byte, 8)
+		server.Read(buf) // Handshake
+		server.Close()
+	}()
+
+	ctx := protocol.WithTarget(context.Background(), protocol.Target{ID: 3, Address: "@3"})
+	p, err := protocol.Handshake(ctx, conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	request, response := newMessagePair(512, 512)
+	protocol.EncodeLeader(&request)
+
+	err = p.Call(context.Background(), &request, &response)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "node 3 (@3): call leader")
+
+	target, ok := err.(*protocol.ErrTarget)
+	require.True(t, ok)
+	assert.Equal(t, protocol.Target{ID: 3, Address: "@3"}, target.Target)
+}
+
+// A request that is only partially written makes the connection unusable.
+func TestProtocol_CallPartialWrite(t *testing.T) {
+	client, server := net.Pipe()
+	go io.Copy(ioutil.Discard, server)
+	defer server.Close()
+
+	conn := &failingConn{Conn: client, budget: 8} // Handshake only
+	p, err := protocol.Handshake(context.Background(), conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	request, response := newMessagePair(512, 512)
+	protocol.EncodeOpen(&request, "test.db", 0, "test-0")
+	_, n := request.Body()
+	size := 8 + n
+	conn.budget = size / 2
+
+	err = p.Call(context.Background(), &request, &response)
+	require.Error(t, err)
+
+	partial, ok := errors.Cause(err).(*protocol.ErrPartialWrite)
+	require.True(t, ok, err)
+	assert.Equal(t, size/2, partial.Written)
+	assert.Equal(t, size, partial.Size)
+
+	// The connection is not used again.
+	conn.budget = size
+	err = p.Call(context.Background(), &request, &response)
+	require.Error(t, err)
+	_, ok = errors.Cause(err).(*protocol.ErrPartialWrite)
+	assert.True(t, ok, err)
+	assert.Equal(t, size, conn.budget)
+}
+
+// The wire log reports the type and size of requests and responses.
+func TestProtocol_WireLog(t *testing.T) {
+	conn, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 8+8+8) // Handshake and Cluster request
+		if _, err := io.ReadFull(server, buf); err != nil {
+			return
+		}
+		response := make([]byte, 16) // Nodes response with no nodes
+		binary.LittleEndian.PutUint32(response[0:], 1)
+		response[4] = protocol.ResponseNodes
+		server.Write(response)
+	}()
+
+	p, err := protocol.Handshake(context.Background(), conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	log, check := newLogFunc(t)
+	p.SetWireLog(log)
+
+	request, response := newMessagePair(512, 512)
+	protocol.EncodeCluster(&request, protocol.ClusterFormatV1)
+
+	require.NoError(t, p.Call(context.Background(), &request, &response))
+
+	check([]string{
+		"DEBUG: wire: send cluster request (16 bytes)",
+		"DEBUG: wire: recv nodes response (16 bytes)",
+	})
+}
+
+// A not-leader failure is returned as ErrNotLeader, carrying the leader
+// reported by the server, and the connection can still be used.
+func TestProtocol_CallNotLeader(t *testing.T) {
+	conn, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		if _, err := io.ReadFull(server, make([]byte, 8)); err != nil { // Handshake
+			return
+		}
+
+		readRequest(server) // Exec request
+		server.Write(newFailureResponse(10|40<<8, "not leader"))
+
+		readRequest(server) // Leader request
+		response := make([]byte, 24)
+		binary.LittleEndian.PutUint32(response[0:], 2)
+		response[4] = protocol.ResponseNode
+		binary.LittleEndian.PutUint64(response[8:], 2)
+		copy(response[16:], "@2")
+		server.Write(response)
+
+		readRequest(server) // Cluster request
+		response = make([]byte, 16)
+		binary.LittleEndian.PutUint32(response[0:], 1)
+		response[4] = protocol.ResponseNodes
+		server.Write(response)
+	}()
+
+	p, err := protocol.Handshake(context.Background(), conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	request, response := newMessagePair(512, 512)
+	protocol.EncodeExecSQLV0(&request, 0, "INSERT INTO test VALUES (1)", nil)
+
+	err = p.Call(context.Background(), &request, &response)
+	var notLeader *protocol.ErrNotLeader
+	require.True(t, errors.As(errors.Wrap(err, "exec"), &notLeader))
+	assert.Equal(t, uint64(10|40<<8), notLeader.Code)
+	assert.Equal(t, &protocol.NodeInfo{ID: 2, Address: "@2"}, notLeader.Leader)
+	assert.EqualError(t, err, "not leader (10250), leader is @2")
+
+	protocol.EncodeCluster(&request, protocol.ClusterFormatV1)
+	require.NoError(t, p.Call(context.Background(), &request, &response))
+	nodes, err := protocol.DecodeNodes(&response)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 0)
+}
+
+// Other failures are left to the response decoders.
+func TestProtocol_CallFailure(t *testing.T) {
+	conn, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		if _, err := io.ReadFull(server, make([]byte, 8)); err != nil { // Handshake
+			return
+		}
+		readRequest(server)
+		server.Write(newFailureResponse(1, "no such table: test"))
+	}()
+
+	p, err := protocol.Handshake(context.Background(), conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	request, response := newMessagePair(512, 512)
+	protocol.EncodeExecSQLV0(&request, 0, "INSERT INTO test VALUES (1)", nil)
+
+	require.NoError(t, p.Call(context.Background(), &request, &response))
+	_, err = protocol.DecodeResult(&response)
+	assert.Equal(t, protocol.ErrRequest{Code: 1, Description: "no such table: test"}, err)
+}
+
+// Read a full request message from the given connection.
+func readRequest(conn net.Conn) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	words := binary.LittleEndian.Uint32(header[0:])
+	io.ReadFull(conn, make([]byte, words*8))
+}
+
+// Return a failure response with the given code and description.
+func newFailureResponse(code uint64, description string) []byte {
+	size := 8 + len(description) + 1
+	if size%8 != 0 {
+		size += 8 - size%8
+	}
+	response := make([]byte, 8+size)
+	binary.LittleEndian.PutUint32(response[0:], uint32(size/8))
+	response[4] = protocol.ResponseFailure
+	binary.LittleEndian.PutUint64(response[8:], code)
+	copy(response[16:], description)
+	return response
+}
+
+// Connection that fails writes once the given budget of bytes is exhausted.
+type failingConn struct {
+	net.Conn
+	budget int
+}
+
+func (c *failingConn) Write(b []byte) (int, error) {
+	if len(b) <= c.budget {
+		c.budget -= len(b)
+		return c.Conn.Write(b)
+	}
+	n, _ := c.Conn.Write(b[:c.budget])
+	c.budget = 0
+	return n, fmt.Errorf("connection reset")
+}
+
 func TestProtocol_Prepare(t *testing.T) {
 	c, cleanup := newProtocol(t)
 	defer cleanup()
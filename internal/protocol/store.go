@@ -74,3 +74,42 @@ func (i *InmemNodeStore) Set(ctx context.Context, servers []NodeInfo) error {
 	i.servers = servers
 	return nil
 }
+
+// LeaderCache remembers the address of the last leader found by a Connector,
+// so that the next Connect can try it first, before iterating the servers of
+// its NodeStore.
+//
+// A LeaderCache can be shared between connectors and is safe for concurrent
+// use.
+type LeaderCache struct {
+	mu      sync.Mutex
+	address string
+}
+
+// NewLeaderCache creates a new empty LeaderCache.
+func NewLeaderCache() *LeaderCache {
+	return &LeaderCache{}
+}
+
+// Get returns the cached leader address, or an empty string if none.
+func (c *LeaderCache) Get() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.address
+}
+
+// Set caches the given leader address.
+func (c *LeaderCache) Set(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.address = address
+}
+
+// Invalidate clears the cached leader address, if it's still the given one.
+func (c *LeaderCache) Invalidate(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.address == address {
+		c.address = ""
+	}
+}
@@ -0,0 +1,61 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+)
+
+// Target identifies the dqlite node a connection or a request is directed to.
+//
+// The ID is zero if not known, for example when connecting to a leader
+// reported by another node.
+type Target struct {
+	ID      uint64
+	Address string
+}
+
+// String implements the Stringer interface.
+func (t Target) String() string {
+	if t.ID == 0 {
+		return t.Address
+	}
+	return fmt.Sprintf("node %d (%s)", t.ID, t.Address)
+}
+
+type targetKey struct{}
+
+// WithTarget returns a copy of ctx carrying the given target.
+//
+// The Connector attaches the node being contacted to the context passed to
+// its DialFunc, and connections established with Handshake remember the
+// target found in the context, so errors returned by Call include it.
+func WithTarget(ctx context.Context, target Target) context.Context {
+	return context.WithValue(ctx, targetKey{}, target)
+}
+
+// TargetFromContext returns the target attached to ctx with WithTarget, if
+// any.
+func TargetFromContext(ctx context.Context) (Target, bool) {
+	target, ok := ctx.Value(targetKey{}).(Target)
+	return target, ok
+}
+
+// ErrTarget wraps an error hit while contacting a specific node.
+type ErrTarget struct {
+	Target Target
+	Err    error
+}
+
+func (e *ErrTarget) Error() string {
+	return fmt.Sprintf("%s: %v", e.Target, e.Err)
+}
+
+// Cause returns the wrapped error, for github.com/pkg/errors.
+func (e *ErrTarget) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the wrapped error, for the standard errors package.
+func (e *ErrTarget) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,59 @@
+package dqlite
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"github.com/canonical/go-dqlite/client"
+)
+
+// Accept connections from the given extra listener and forward them to the
+// node's bind address, until the listener gets closed.
+func (s *Node) forward(listener net.Listener) {
+	defer s.forwarding.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go forwardConn(s.ctx, conn, s.BindAddress())
+	}
+}
+
+// Copy data back and forth between the given connection and a new connection
+// to the given local address, until either side is closed or the context is
+// done.
+func forwardConn(ctx context.Context, conn net.Conn, address string) {
+	defer conn.Close()
+
+	local, err := client.DefaultDialFunc(ctx, address)
+	if err != nil {
+		return
+	}
+	defer local.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(local, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, local)
+		done <- struct{}{}
+	}()
+
+	// Closing both connections on return unblocks the other copy.
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// Close the extra listeners and wait for their accept loops to exit.
+func (s *Node) closeListeners() {
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	s.forwarding.Wait()
+}
@@ -2,6 +2,8 @@ package logging
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -24,3 +26,89 @@ func Stdout() Func {
 		fmt.Printf(format, a...)
 	}
 }
+
+// LevelFilter wraps a logging function, dropping messages below a minimum
+// level. The level can be changed at any time, also while the function is
+// being used.
+type LevelFilter struct {
+	f     Func
+	level int32 // MUST be accessed atomically
+}
+
+// NewLevelFilter returns a LevelFilter forwarding to f the messages whose
+// level is at least the given one.
+func NewLevelFilter(f Func, level Level) *LevelFilter {
+	return &LevelFilter{f: f, level: int32(level)}
+}
+
+// SetLevel changes the minimum level of the forwarded messages.
+func (lf *LevelFilter) SetLevel(level Level) {
+	atomic.StoreInt32(&lf.level, int32(level))
+}
+
+// Level returns the current minimum level of the forwarded messages.
+func (lf *LevelFilter) Level() Level {
+	return Level(atomic.LoadInt32(&lf.level))
+}
+
+// Func returns a logging function applying the filter, which can be passed to
+// options such as client.WithLogFunc.
+func (lf *LevelFilter) Func() Func {
+	return func(l Level, format string, a ...interface{}) {
+		if l < lf.Level() {
+			return
+		}
+		lf.f(l, format, a...)
+	}
+}
+
+// RingBuffer wraps a logging function, also keeping the most recent messages
+// in memory, so they can be inspected after an incident.
+type RingBuffer struct {
+	f     Func
+	mu    sync.Mutex
+	lines []string // Retained messages, used as a circular buffer
+	next  int      // Index in lines where the next message goes
+	full  bool     // Whether lines has wrapped around
+}
+
+// NewRingBuffer returns a RingBuffer retaining the last size messages and
+// forwarding all messages to f, unless f is nil.
+func NewRingBuffer(f Func, size int) *RingBuffer {
+	if size < 0 {
+		size = 0
+	}
+	return &RingBuffer{f: f, lines: make([]string, size)}
+}
+
+// Lines returns the retained messages, oldest first, each prefixed with its
+// level.
+func (rb *RingBuffer) Lines() []string {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if !rb.full {
+		return append([]string{}, rb.lines[:rb.next]...)
+	}
+	return append(append([]string{}, rb.lines[rb.next:]...), rb.lines[:rb.next]...)
+}
+
+// Func returns a logging function recording messages in the buffer, which can
+// be passed to options such as client.WithLogFunc.
+func (rb *RingBuffer) Func() Func {
+	return func(l Level, format string, a ...interface{}) {
+		if n := len(rb.lines); n > 0 {
+			line := fmt.Sprintf("%s: %s", l.String(), fmt.Sprintf(format, a...))
+			rb.mu.Lock()
+			rb.lines[rb.next] = line
+			rb.next = (rb.next + 1) % n
+			if rb.next == 0 {
+				rb.full = true
+			}
+			rb.mu.Unlock()
+		}
+		if rb.f != nil {
+			rb.f(l, format, a...)
+		}
+	}
+}
@@ -1,12 +1,69 @@
 package logging_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/canonical/go-dqlite/logging"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_TestFunc(t *testing.T) {
 	f := logging.Test(t)
 	f(logging.Info, "hello")
 }
+
+func TestLevelFilter(t *testing.T) {
+	messages := []string{}
+	f := func(l logging.Level, format string, a ...interface{}) {
+		messages = append(messages, fmt.Sprintf("%s: "+format, append([]interface{}{l}, a...)...))
+	}
+
+	filter := logging.NewLevelFilter(f, logging.Debug)
+	log := filter.Func()
+
+	log(logging.Debug, "debug %d", 1)
+	log(logging.Error, "error %d", 1)
+
+	filter.SetLevel(logging.Error)
+	assert.Equal(t, logging.Error, filter.Level())
+
+	log(logging.Debug, "debug %d", 2)
+	log(logging.Warn, "warn %d", 2)
+	log(logging.Error, "error %d", 2)
+
+	filter.SetLevel(logging.Debug)
+
+	log(logging.Debug, "debug %d", 3)
+
+	assert.Equal(t, []string{
+		"DEBUG: debug 1",
+		"ERROR: error 1",
+		"ERROR: error 2",
+		"DEBUG: debug 3",
+	}, messages)
+}
+
+func TestRingBuffer(t *testing.T) {
+	messages := []string{}
+	f := func(l logging.Level, format string, a ...interface{}) {
+		messages = append(messages, fmt.Sprintf(format, a...))
+	}
+
+	buffer := logging.NewRingBuffer(f, 3)
+	log := buffer.Func()
+
+	assert.Empty(t, buffer.Lines())
+
+	log(logging.Info, "line %d", 1)
+	log(logging.Warn, "line %d", 2)
+	assert.Equal(t, []string{"INFO: line 1", "WARN: line 2"}, buffer.Lines())
+
+	for i := 3; i <= 7; i++ {
+		log(logging.Debug, "line %d", i)
+	}
+	assert.Equal(t, []string{"DEBUG: line 5", "DEBUG: line 6", "DEBUG: line 7"}, buffer.Lines())
+
+	// All messages are forwarded.
+	assert.Len(t, messages, 7)
+}
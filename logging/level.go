@@ -3,7 +3,8 @@ package logging
 // Level defines the logging level.
 type Level int
 
-// Available logging levels.
+// Available logging levels, in order of increasing severity. Levels can be
+// compared with the usual operators, e.g. Debug < Info.
 const (
 	None Level = iota
 	Debug
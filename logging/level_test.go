@@ -16,3 +16,10 @@ func TestLevel_String(t *testing.T) {
 	unknown := logging.Level(666)
 	assert.Equal(t, "UNKNOWN", unknown.String())
 }
+
+func TestLevel_Ordering(t *testing.T) {
+	assert.True(t, logging.None < logging.Debug)
+	assert.True(t, logging.Debug < logging.Info)
+	assert.True(t, logging.Info < logging.Warn)
+	assert.True(t, logging.Warn < logging.Error)
+}
@@ -2,6 +2,16 @@ package dqlite
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/canonical/go-dqlite/client"
@@ -11,13 +21,23 @@ import (
 
 // Node runs a dqlite node.
 type Node struct {
-	log         client.LogFunc // Logger
-	server      *bindings.Node // Low-level C implementation
-	acceptCh    chan error     // Receives connection handling errors
+	log         client.LogFunc         // Logger
+	server      bindings.NodeInterface // Low-level C implementation
+	acceptCh    chan error             // Receives connection handling errors
 	id          uint64
 	address     string
 	bindAddress string
+	dir         string
+	dial        client.DialFunc
+	ctx         context.Context
 	cancel      context.CancelFunc
+	listeners   []net.Listener // Extra listeners forwarding to the bind address
+	forwarding  sync.WaitGroup // Tracks the accept loops of the extra listeners
+	priority    int            // Leadership priority, 0 if not set
+	reclaiming  sync.WaitGroup // Tracks the leadership priority loop
+	started     int32          // Non-zero once Start() succeeds, MUST be accessed atomically
+	closed      int32          // Non-zero once Close() is called, MUST be accessed atomically
+	onStop      func() error   // Invoked by Close before stopping the server
 }
 
 // NodeInfo is a convenience alias for client.NodeInfo.
@@ -48,6 +68,81 @@ func WithBindAddress(address string) Option {
 	}
 }
 
+// WithNodeBindRetry makes the node retry binding to an abstract Unix socket
+// bind address up to the given number of times if it fails, for example
+// because a previous process using the same address hasn't released it yet.
+//
+// Only failures caused by the address being in use are retried. The delay
+// between attempts starts at the given interval and doubles after every
+// failed attempt, so New might block for up to interval*(2^attempts-1). By
+// default no retry is made.
+func WithNodeBindRetry(attempts int, interval time.Duration) Option {
+	return func(options *options) {
+		options.BindRetryAttempts = attempts
+		options.BindRetryInterval = interval
+	}
+}
+
+// WithNodeExtraListener makes the node also accept connections from the given
+// listener, in addition to the ones on its bind address.
+//
+// Connections accepted by the listener are forwarded to the bind address, so
+// requests are served identically on both. This can be used for example to
+// handle cluster traffic over TCP while exposing a local Unix socket for
+// management. The option can be passed multiple times and the node takes
+// ownership of the listeners, closing them when the node is closed.
+func WithNodeExtraListener(listener net.Listener) Option {
+	return func(options *options) {
+		options.ExtraListeners = append(options.ExtraListeners, listener)
+	}
+}
+
+// WithNodeLeadershipPriority makes the node reclaim leadership whenever the
+// current leader has a lower priority, which approximates a preferred leader.
+//
+// Once started, the node records its priority in the cluster with
+// client.Client.SetLeadershipPriority and then checks the current leader every
+// second: if the priority recorded for the leader is lower than its own, and
+// this node is a voter, it asks the leader to transfer leadership to it. Nodes
+// without a recorded priority have priority zero. The priority must not be
+// negative, and zero disables the behavior.
+func WithNodeLeadershipPriority(priority int) Option {
+	return func(options *options) {
+		options.LeadershipPriority = priority
+	}
+}
+
+// WithNodeSkipAddressValidation disables the check that New performs on the
+// node address and bind address, for transports using custom address formats.
+func WithNodeSkipAddressValidation() Option {
+	return func(options *options) {
+		options.SkipAddressValidation = true
+	}
+}
+
+// WithNodeCreateDir makes New create the data directory with the given
+// permissions if it doesn't exist, along with any missing parent. By default
+// New fails if the data directory is missing.
+func WithNodeCreateDir(mode os.FileMode) Option {
+	return func(options *options) {
+		options.CreateDir = true
+		options.CreateDirMode = mode
+	}
+}
+
+// WithNodeOnStop sets a function that Close and CloseWithTimeout invoke once,
+// right before stopping the dqlite event loop, while the node is still
+// serving requests on its bind address.
+//
+// A failure of the function doesn't prevent the node from shutting down: the
+// error is returned by Close once the node is fully stopped, unless stopping
+// the node fails too.
+func WithNodeOnStop(f func() error) Option {
+	return func(options *options) {
+		options.OnStop = f
+	}
+}
+
 // WithNetworkLatency sets the average one-way network latency.
 func WithNetworkLatency(latency time.Duration) Option {
 	return func(options *options) {
@@ -95,6 +190,13 @@ func WithAutoRecovery(recovery bool) Option {
 }
 
 // New creates a new Node instance.
+//
+// The address, and the bind address if set, must be either an abstract Unix
+// socket address starting with "@" or a host:port pair, unless
+// WithNodeSkipAddressValidation is used.
+//
+// The data directory must be an existing writable directory, unless
+// WithNodeCreateDir is used and it doesn't exist yet.
 func New(id uint64, address string, dir string, options ...Option) (*Node, error) {
 	o := defaultOptions()
 
@@ -102,6 +204,10 @@ func New(id uint64, address string, dir string, options ...Option) (*Node, error
 		option(o)
 	}
 
+	if err := checkDir(dir, o.CreateDir, o.CreateDirMode); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	server, err := bindings.NewNode(ctx, id, address, dir)
 	if err != nil {
@@ -109,6 +215,30 @@ func New(id uint64, address string, dir string, options ...Option) (*Node, error
 		return nil, err
 	}
 
+	return newNode(ctx, cancel, server, id, address, dir, o)
+}
+
+// Create a new Node instance wrapping the given low-level server, configuring
+// it with the given options.
+func newNode(ctx context.Context, cancel context.CancelFunc, server bindings.NodeInterface, id uint64, address string, dir string, o *options) (*Node, error) {
+	if o.LeadershipPriority < 0 {
+		cancel()
+		return nil, fmt.Errorf("invalid leadership priority %d", o.LeadershipPriority)
+	}
+
+	if !o.SkipAddressValidation {
+		if err := validateAddress(address); err != nil {
+			cancel()
+			return nil, errors.Wrapf(err, "invalid address %q", address)
+		}
+		if o.BindAddress != "" {
+			if err := validateAddress(o.BindAddress); err != nil {
+				cancel()
+				return nil, errors.Wrapf(err, "invalid bind address %q", o.BindAddress)
+			}
+		}
+	}
+
 	if o.DialFunc != nil {
 		if err := server.SetDialFunc(o.DialFunc); err != nil {
 			cancel()
@@ -116,7 +246,7 @@ func New(id uint64, address string, dir string, options ...Option) (*Node, error
 		}
 	}
 	if o.BindAddress != "" {
-		if err := server.SetBindAddress(o.BindAddress); err != nil {
+		if err := setBindAddress(server, o.BindAddress, o.BindRetryAttempts, o.BindRetryInterval); err != nil {
 			cancel()
 			return nil, err
 		}
@@ -156,12 +286,102 @@ func New(id uint64, address string, dir string, options ...Option) (*Node, error
 		id:          id,
 		address:     address,
 		bindAddress: o.BindAddress,
+		dir:         dir,
+		dial:        o.DialFunc,
+		ctx:         ctx,
 		cancel:      cancel,
+		listeners:   o.ExtraListeners,
+		priority:    o.LeadershipPriority,
+		onStop:      o.OnStop,
 	}
 
 	return s, nil
 }
 
+// Check that the given address is either an abstract Unix socket address or a
+// host:port pair with a numeric port.
+func validateAddress(address string) error {
+	if strings.HasPrefix(address, "@") {
+		if len(address) == 1 {
+			return fmt.Errorf("empty abstract socket name")
+		}
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+// Check that the given data directory exists and is writable, creating it
+// with the given permissions if it's missing and create is true.
+func checkDir(dir string, create bool, mode os.FileMode) error {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) && create {
+		if err := os.MkdirAll(dir, mode); err != nil {
+			return errors.Wrapf(err, "create data directory %q", dir)
+		}
+		info, err = os.Stat(dir)
+	}
+	if os.IsNotExist(err) {
+		return fmt.Errorf("data directory %q does not exist", dir)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "check data directory %q", dir)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("data directory %q is not a directory", dir)
+	}
+
+	file, err := ioutil.TempFile(dir, ".dqlite-check-")
+	if err != nil {
+		return errors.Wrapf(err, "data directory %q is not writable", dir)
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	return nil
+}
+
+// Set the bind address of the given server, possibly retrying if it's an
+// abstract Unix socket which is currently in use.
+func setBindAddress(server bindings.NodeInterface, address string, attempts int, interval time.Duration) error {
+	err := server.SetBindAddress(address)
+	if !strings.HasPrefix(address, "@") {
+		return err
+	}
+	for i := 0; err != nil && i < attempts; i++ {
+		if !abstractAddressInUse(address) {
+			// Not a transient failure, no point in retrying.
+			return err
+		}
+		time.Sleep(interval)
+		interval *= 2
+		err = server.SetBindAddress(address)
+	}
+	return err
+}
+
+// Return true if the given abstract Unix socket address is bound by someone
+// else.
+//
+// The C library doesn't report why binding failed, so probe the address
+// ourselves.
+func abstractAddressInUse(address string) bool {
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		return errors.Is(err, syscall.EADDRINUSE)
+	}
+	listener.Close()
+	return false
+}
+
 // BindAddress returns the network address the node is listening to.
 func (s *Node) BindAddress() string {
 	return s.server.GetBindAddress()
@@ -169,7 +389,19 @@ func (s *Node) BindAddress() string {
 
 // Start serving requests.
 func (s *Node) Start() error {
-	return s.server.Start()
+	if err := s.server.Start(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&s.started, 1)
+	for _, listener := range s.listeners {
+		s.forwarding.Add(1)
+		go s.forward(listener)
+	}
+	if s.priority > 0 {
+		s.reclaiming.Add(1)
+		go s.reclaimLeadershipLoop(s.BindAddress())
+	}
+	return nil
 }
 
 // Recover a node by forcing a new cluster configuration.
@@ -180,21 +412,431 @@ func (s *Node) Recover(cluster []NodeInfo) error {
 	return s.server.Recover(cluster)
 }
 
+// Promote asks the current cluster leader to assign the Voter role to this
+// node.
+//
+// The given store is used to find the leader and the given dial function is
+// used to connect to it; if dial is nil, client.DefaultDialFunc is used. If
+// the node is already a voter, nothing happens.
+func (s *Node) Promote(ctx context.Context, store client.NodeStore, dial client.DialFunc) error {
+	return s.assignRole(ctx, store, dial, client.Voter)
+}
+
+// Demote asks the current cluster leader to assign the StandBy role to this
+// node, if it's currently a voter.
+//
+// The given store is used to find the leader and the given dial function is
+// used to connect to it; if dial is nil, client.DefaultDialFunc is used. If
+// the node is already a stand-by or a spare, nothing happens.
+func (s *Node) Demote(ctx context.Context, store client.NodeStore, dial client.DialFunc) error {
+	return s.assignRole(ctx, store, dial, client.StandBy)
+}
+
+// TransferTo transfers leadership from this node to the voter with the given
+// ID.
+//
+// It must be called on the current leader, and an error is returned if this
+// node is not the leader or if the target is not a voter.
+func (s *Node) TransferTo(ctx context.Context, id uint64) error {
+	cli, nodes, err := s.connectAsLeader(ctx)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	eligible := false
+	for _, node := range nodes {
+		if node.ID == id && node.ID != s.id && node.Role == client.Voter {
+			eligible = true
+			break
+		}
+	}
+	if !eligible {
+		return fmt.Errorf("node %d is not an eligible voter", id)
+	}
+
+	if err := cli.Transfer(ctx, id); err != nil {
+		return errors.Wrapf(err, "transfer leadership to node %d", id)
+	}
+
+	return nil
+}
+
+// StepDown makes this node, which must be the current leader, relinquish
+// leadership in favor of another voter.
+//
+// Unlike TransferTo, the new leader is not chosen by the caller: raft picks
+// the voter whose log is most up to date.
+func (s *Node) StepDown(ctx context.Context) error {
+	cli, nodes, err := s.connectAsLeader(ctx)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	voters := 0
+	for _, node := range nodes {
+		if node.ID != s.id && node.Role == client.Voter {
+			voters++
+		}
+	}
+	if voters == 0 {
+		return fmt.Errorf("no other voter to hand leadership to")
+	}
+
+	// An ID of zero lets raft choose the target.
+	if err := cli.Transfer(ctx, 0); err != nil {
+		return errors.Wrap(err, "step down")
+	}
+
+	return nil
+}
+
+// Connect to this node, check that it's the leader and return the current
+// cluster configuration.
+func (s *Node) connectAsLeader(ctx context.Context) (*client.Client, []client.NodeInfo, error) {
+	cli, err := client.New(ctx, s.BindAddress())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "connect to local node")
+	}
+
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		cli.Close()
+		return nil, nil, errors.Wrap(err, "get leader")
+	}
+	if leader == nil || leader.ID != s.id {
+		cli.Close()
+		return nil, nil, fmt.Errorf("node %d is not the leader", s.id)
+	}
+
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		cli.Close()
+		return nil, nil, errors.Wrap(err, "get cluster servers")
+	}
+
+	return cli, nodes, nil
+}
+
+// LastSnapshot returns information about the most recent raft snapshot taken
+// by this node, as found in its data directory.
+//
+// If the node has not taken any snapshot yet, a zero SnapshotInfo is
+// returned. The context is only checked for cancellation before reading the
+// data directory.
+func (s *Node) LastSnapshot(ctx context.Context) (*client.SnapshotInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "read data directory")
+	}
+
+	last := &client.SnapshotInfo{}
+	for _, entry := range entries {
+		// Raft writes the metadata file only once the snapshot is
+		// complete, so only consider those.
+		var term, index, timestamp uint64
+		n, _ := fmt.Sscanf(entry.Name(), "snapshot-%d-%d-%d.meta", &term, &index, &timestamp)
+		if n != 3 || !strings.HasSuffix(entry.Name(), ".meta") {
+			continue
+		}
+		if index < last.Index || (index == last.Index && term < last.Term) {
+			continue
+		}
+		last.Index = index
+		last.Term = term
+		last.Timestamp = time.Unix(0, int64(timestamp)*int64(time.Millisecond))
+	}
+
+	return last, nil
+}
+
+// NodeDescription holds the full self-description of a node.
+type NodeDescription struct {
+	client.NodeInfo
+	client.NodeMetadata
+}
+
+// Info returns the ID, address, role and metadata of this node, as currently
+// known by the node itself.
+//
+// The role is taken from the cluster configuration the node has replicated so
+// far, so it might lag behind a change just made on the leader.
+func (s *Node) Info(ctx context.Context) (*NodeDescription, error) {
+	cli, err := client.New(ctx, s.BindAddress())
+	if err != nil {
+		return nil, errors.Wrap(err, "connect to local node")
+	}
+	defer cli.Close()
+
+	metadata, err := cli.Describe(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "describe node")
+	}
+
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get cluster servers")
+	}
+
+	for _, node := range nodes {
+		if node.ID == s.id {
+			return &NodeDescription{NodeInfo: node, NodeMetadata: *metadata}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("node %d is not part of the cluster", s.id)
+}
+
+// SQLiteConfig returns the SQLite settings in effect for the database with
+// the given name on this node, such as its page size and journal mode.
+//
+// Databases are served only by the leader, so this node must be the
+// current leader.
+func (s *Node) SQLiteConfig(ctx context.Context, dbname string) (*client.SQLiteConfig, error) {
+	cli, err := client.New(ctx, s.BindAddress())
+	if err != nil {
+		return nil, errors.Wrap(err, "connect to local node")
+	}
+	defer cli.Close()
+
+	return cli.SQLiteConfig(ctx, dbname)
+}
+
+// ClusterStream invokes the given function with each node of the cluster
+// configuration known by this node, decoding them one at a time.
+//
+// If the function returns an error, iteration stops and the error is
+// returned.
+func (s *Node) ClusterStream(ctx context.Context, f func(client.NodeInfo) error) error {
+	cli, err := client.New(ctx, s.BindAddress())
+	if err != nil {
+		return errors.Wrap(err, "connect to local node")
+	}
+	defer cli.Close()
+
+	return cli.ClusterStream(ctx, f)
+}
+
+// Assign the given role to this node, unless it has it already. Demoting a
+// spare node to stand-by is considered a no-op.
+func (s *Node) assignRole(ctx context.Context, store client.NodeStore, dial client.DialFunc, role client.NodeRole) error {
+	if dial == nil {
+		dial = client.DefaultDialFunc
+	}
+
+	cli, err := client.FindLeader(ctx, store, client.WithDialFunc(dial))
+	if err != nil {
+		return errors.Wrap(err, "find leader")
+	}
+	defer cli.Close()
+
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get cluster servers")
+	}
+
+	var current *NodeInfo
+	for i := range nodes {
+		if nodes[i].ID == s.id {
+			current = &nodes[i]
+			break
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("node %d is not part of the cluster", s.id)
+	}
+
+	if current.Role == role || (role == client.StandBy && current.Role == client.Spare) {
+		return nil
+	}
+
+	if err := cli.Assign(ctx, s.id, role); err != nil {
+		return errors.Wrapf(err, "assign %s role", role)
+	}
+
+	return nil
+}
+
+// HealthOptions can be used to tweak the checks performed by Node.Health.
+type HealthOptions struct {
+	// Maximum time to wait for the leader to reply, 5 seconds by default.
+	LeaderTimeout time.Duration
+}
+
+// Health checks whether this node is started and whether it can reach the
+// current cluster leader.
+//
+// The apply lag of the node is not checked, since dqlite does not expose the
+// index of the last entry applied by a node.
+//
+// An error is returned only if the given context is already done; failed
+// checks are reported in the returned HealthReport.
+func (s *Node) Health(ctx context.Context, opts HealthOptions) (*client.HealthReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.LeaderTimeout == 0 {
+		opts.LeaderTimeout = 5 * time.Second
+	}
+
+	report := &client.HealthReport{}
+
+	report.Started = atomic.LoadInt32(&s.started) == 1
+	if !report.Started {
+		report.Reason = "node not started"
+		return report, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.LeaderTimeout)
+	defer cancel()
+
+	local, err := client.New(ctx, s.BindAddress())
+	if err != nil {
+		report.Reason = fmt.Sprintf("local node unreachable: %v", err)
+		return report, nil
+	}
+	defer local.Close()
+
+	leader, err := local.Leader(ctx)
+	if err != nil {
+		report.Reason = fmt.Sprintf("get leader: %v", err)
+		return report, nil
+	}
+	if leader.ID == 0 {
+		report.Reason = "no known leader"
+		return report, nil
+	}
+	report.Leader = leader
+
+	remote, err := client.New(ctx, leader.Address, client.WithDialFunc(s.dial))
+	if err == nil {
+		_, err = remote.Leader(ctx)
+		remote.Close()
+	}
+	if err != nil {
+		report.Reason = fmt.Sprintf("leader unreachable: %v", err)
+		return report, nil
+	}
+	report.LeaderReachable = true
+
+	report.OK = true
+
+	return report, nil
+}
+
 // Hold configuration options for a dqlite server.
 type options struct {
-	Log            client.LogFunc
-	DialFunc       client.DialFunc
-	BindAddress    string
-	NetworkLatency uint64
-	FailureDomain  uint64
-	SnapshotParams bindings.SnapshotParams
-	DiskMode       bool
-	AutoRecovery   bool
+	Log                   client.LogFunc
+	DialFunc              client.DialFunc
+	BindAddress           string
+	BindRetryAttempts     int
+	BindRetryInterval     time.Duration
+	NetworkLatency        uint64
+	FailureDomain         uint64
+	SnapshotParams        bindings.SnapshotParams
+	DiskMode              bool
+	AutoRecovery          bool
+	ExtraListeners        []net.Listener
+	LeadershipPriority    int
+	SkipAddressValidation bool
+	CreateDir             bool
+	CreateDirMode         os.FileMode
+	OnStop                func() error
+}
+
+// Minimum free space in the data directory required by Preflight.
+const preflightMinFreeSpace = 64 * 1024 * 1024
+
+// Preflight checks that this node has what it needs to start, without
+// starting it: that the data directory is still writable, that its file
+// system has at least 64MiB of free space, and that the node's address can
+// be bound.
+//
+// If a bind address was set with WithBindAddress, it was already bound when
+// the node was created, so the check only reports it. Otherwise the node
+// will listen on its own address, which is probed by binding and releasing
+// it.
+//
+// All checks are run and reported, even if some of them fail. An error is
+// returned only if the context is done or the node was already started.
+func (s *Node) Preflight(ctx context.Context) ([]client.PreflightResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if atomic.LoadInt32(&s.started) == 1 {
+		return nil, fmt.Errorf("node %d already started", s.id)
+	}
+
+	results := []client.PreflightResult{}
+
+	result := client.PreflightResult{Name: "data-dir", OK: true}
+	if err := checkDir(s.dir, false, 0); err != nil {
+		result.OK = false
+		result.Detail = fmt.Sprintf("%v: check the ownership and permissions of the directory", err)
+	} else {
+		result.Detail = fmt.Sprintf("data directory %q is writable", s.dir)
+	}
+	results = append(results, result)
+
+	result = client.PreflightResult{Name: "disk-space", OK: true}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(s.dir, &stat); err != nil {
+		result.OK = false
+		result.Detail = fmt.Sprintf("get file system stats: %v", err)
+	} else {
+		free := stat.Bavail * uint64(stat.Bsize)
+		result.Detail = fmt.Sprintf("%d bytes free in %q", free, s.dir)
+		if free < preflightMinFreeSpace {
+			result.OK = false
+			result.Detail += fmt.Sprintf(", at least %d are needed: free up space", preflightMinFreeSpace)
+		}
+	}
+	results = append(results, result)
+
+	result = client.PreflightResult{Name: "bind-address", OK: true}
+	if s.bindAddress != "" {
+		result.Detail = fmt.Sprintf("bound to %s", s.BindAddress())
+	} else if err := probeAddress(s.address); err != nil {
+		result.OK = false
+		result.Detail = fmt.Sprintf("%v: stop the process using %s or choose another address", err, s.address)
+	} else {
+		result.Detail = fmt.Sprintf("address %s is free", s.address)
+	}
+	results = append(results, result)
+
+	return results, nil
+}
+
+// Check that the given address can be bound, releasing it right away.
+func probeAddress(address string) error {
+	network := "tcp"
+	if strings.HasPrefix(address, "@") {
+		network = "unix"
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	return listener.Close()
 }
 
 // Close the server, releasing all resources it created.
+//
+// Calling Close more than once is a no-op.
 func (s *Node) Close() error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
 	s.cancel()
+	s.closeListeners()
+	s.reclaiming.Wait()
+	hookErr := s.runOnStop()
 	// Send a stop signal to the dqlite event loop.
 	if err := s.server.Stop(); err != nil {
 		return errors.Wrap(err, "server failed to stop")
@@ -202,9 +844,64 @@ func (s *Node) Close() error {
 
 	s.server.Close()
 
+	return hookErr
+}
+
+// CloseWithTimeout is like Close, but stops waiting for the dqlite event loop
+// to stop after the given timeout, returning an error.
+//
+// Releasing the node resources while the event loop is still running is not
+// safe, so in that case they are released in the background once the event
+// loop eventually stops.
+func (s *Node) CloseWithTimeout(timeout time.Duration) error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
+	s.cancel()
+	s.closeListeners()
+	s.reclaiming.Wait()
+	hookErr := s.runOnStop()
+	if err := closeWithTimeout(s.server.Stop, s.server.Close, timeout); err != nil {
+		return err
+	}
+	return hookErr
+}
+
+// Invoke the function set with WithNodeOnStop, if any.
+func (s *Node) runOnStop() error {
+	if s.onStop == nil {
+		return nil
+	}
+	if err := s.onStop(); err != nil {
+		return errors.Wrap(err, "stop hook failed")
+	}
 	return nil
 }
 
+// Invoke stop and then release, waiting at most the given timeout for stop to
+// return. If stop times out, release is invoked when stop returns, unless it
+// fails.
+func closeWithTimeout(stop func() error, release func(), timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		err := stop()
+		if err == nil {
+			release()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return errors.Wrap(err, "server failed to stop")
+		}
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("server did not stop within %s", timeout)
+	}
+}
+
 // BootstrapID is a magic ID that should be used for the fist node in a
 // cluster. Alternatively ID 1 can be used as well.
 const BootstrapID = 0x2dc171858c3155be
@@ -246,6 +943,35 @@ func ReconfigureMembershipExt(dir string, cluster []NodeInfo) error {
 	return server.RecoverExt(cluster)
 }
 
+// Name of the file in a node's data directory listing the known cluster nodes.
+const clusterYAML = "cluster.yaml"
+
+// ReadClusterYAML returns the nodes listed in the cluster.yaml file in the
+// given data directory, using the same format as the one maintained by the app
+// package and the dqlite shell.
+//
+// If the file does not exist, an empty list is returned.
+func ReadClusterYAML(dir string) ([]NodeInfo, error) {
+	store, err := client.NewYamlNodeStore(filepath.Join(dir, clusterYAML))
+	if err != nil {
+		return nil, errors.Wrap(err, "read cluster.yaml")
+	}
+	return store.Get(context.Background())
+}
+
+// WriteClusterYAML atomically replaces the cluster.yaml file in the given data
+// directory with the given nodes.
+func WriteClusterYAML(dir string, nodes []NodeInfo) error {
+	store, err := client.NewYamlNodeStore(filepath.Join(dir, clusterYAML))
+	if err != nil {
+		return errors.Wrap(err, "open cluster.yaml")
+	}
+	if err := store.Set(context.Background(), nodes); err != nil {
+		return errors.Wrap(err, "write cluster.yaml")
+	}
+	return nil
+}
+
 // Create a options object with sane defaults.
 func defaultOptions() *options {
 	return &options{
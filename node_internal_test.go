@@ -0,0 +1,397 @@
+package dqlite
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/internal/bindings"
+	"github.com/canonical/go-dqlite/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Closing a node more than once stops and releases the server only once.
+func TestNode_CloseIdempotent(t *testing.T) {
+	server := &fakeServer{}
+	node := newFakeNode(t, server)
+
+	require.NoError(t, node.Start())
+	assert.NoError(t, node.Close())
+	assert.NoError(t, node.Close())
+	assert.NoError(t, node.CloseWithTimeout(time.Second))
+
+	assert.Equal(t, []string{"dial", "auto-recovery true", "start", "stop", "close"}, server.calls)
+}
+
+// Options are applied to the underlying server.
+func TestNode_Options(t *testing.T) {
+	server := &fakeServer{}
+	newFakeNode(t, server,
+		WithBindAddress("127.0.0.1:9001"),
+		WithNetworkLatency(20*time.Millisecond),
+		WithFailureDomain(3),
+		WithSnapshotParams(SnapshotParams{Threshold: 8, Trailing: 4}),
+		WithDiskMode(true),
+		WithAutoRecovery(false),
+	)
+
+	assert.Equal(t, []string{
+		"dial",
+		"bind 127.0.0.1:9001",
+		"latency 20000000",
+		"failure domain 3",
+		"snapshot 8 4",
+		"disk mode",
+		"auto-recovery false",
+	}, server.calls)
+}
+
+// Failures while applying options are returned by newNode.
+func TestNode_OptionsError(t *testing.T) {
+	server := &fakeServer{bindErrs: []error{fmt.Errorf("boom")}}
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := newNode(ctx, cancel, server, 1, "@1", t.TempDir(), newOptions(WithBindAddress("127.0.0.1:9001")))
+	assert.EqualError(t, err, "boom")
+	assert.Error(t, ctx.Err())
+}
+
+// Bind failures are not retried if the address is not in use.
+func TestNode_BindRetryPermanentFailure(t *testing.T) {
+	boom := fmt.Errorf("boom")
+	server := &fakeServer{bindErrs: []error{boom, boom, boom}}
+	ctx, cancel := context.WithCancel(context.Background())
+	options := newOptions(WithBindAddress("@dqlite-test-free"), WithNodeBindRetry(5, time.Second))
+	_, err := newNode(ctx, cancel, server, 1, "@1", t.TempDir(), options)
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, []string{"dial", "bind @dqlite-test-free"}, server.calls)
+}
+
+// Bind failures are retried while the address is in use.
+func TestNode_BindRetryInUse(t *testing.T) {
+	listener, err := net.Listen("unix", "@dqlite-test-busy")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	boom := fmt.Errorf("boom")
+	server := &fakeServer{bindErrs: []error{boom, boom}}
+	ctx, cancel := context.WithCancel(context.Background())
+	options := newOptions(WithBindAddress("@dqlite-test-busy"), WithNodeBindRetry(5, time.Millisecond))
+	_, err = newNode(ctx, cancel, server, 1, "@1", t.TempDir(), options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"dial",
+		"bind @dqlite-test-busy",
+		"bind @dqlite-test-busy",
+		"bind @dqlite-test-busy",
+		"auto-recovery true",
+	}, server.calls)
+}
+
+func TestNode_InvalidLeadershipPriority(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := newNode(ctx, cancel, &fakeServer{}, 1, "@1", t.TempDir(), newOptions(WithNodeLeadershipPriority(-1)))
+	assert.EqualError(t, err, "invalid leadership priority -1")
+}
+
+// Malformed addresses are rejected before touching the server.
+func TestNode_InvalidAddress(t *testing.T) {
+	cases := map[string]string{
+		"127.0.0.1":       `invalid address "127.0.0.1": address 127.0.0.1: missing port in address`,
+		"127.0.0.1:http":  `invalid address "127.0.0.1:http": invalid port "http"`,
+		"127.0.0.1:99999": `invalid address "127.0.0.1:99999": invalid port "99999"`,
+		"@":               `invalid address "@": empty abstract socket name`,
+	}
+	for address, message := range cases {
+		t.Run(address, func(t *testing.T) {
+			server := &fakeServer{}
+			ctx, cancel := context.WithCancel(context.Background())
+			_, err := newNode(ctx, cancel, server, 1, address, t.TempDir(), newOptions())
+			assert.EqualError(t, err, message)
+			assert.Empty(t, server.calls)
+		})
+	}
+}
+
+func TestNode_InvalidBindAddress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := newNode(ctx, cancel, &fakeServer{}, 1, "@1", t.TempDir(), newOptions(WithBindAddress("bogus")))
+	assert.EqualError(t, err, `invalid bind address "bogus": address bogus: missing port in address`)
+}
+
+func TestNode_ValidAddress(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:9001", "[::1]:9001", "node1.example.com:9001", "@dqlite-1"} {
+		t.Run(address, func(t *testing.T) {
+			newFakeNode(t, &fakeServer{}, WithBindAddress(address))
+		})
+	}
+}
+
+func TestNode_SkipAddressValidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := newNode(ctx, cancel, &fakeServer{}, 1, "custom://node1", t.TempDir(), newOptions(WithNodeSkipAddressValidation()))
+	assert.NoError(t, err)
+}
+
+// The stop hook runs once, before the server is stopped.
+func TestNode_OnStop(t *testing.T) {
+	server := &fakeServer{}
+	node := newFakeNode(t, server, WithNodeOnStop(func() error {
+		server.record("hook")
+		return nil
+	}))
+
+	require.NoError(t, node.Start())
+	assert.NoError(t, node.Close())
+	assert.NoError(t, node.CloseWithTimeout(time.Second))
+
+	assert.Equal(t, []string{"dial", "auto-recovery true", "start", "hook", "stop", "close"}, server.calls)
+}
+
+// A failing stop hook doesn't prevent the server from being stopped.
+func TestNode_OnStopError(t *testing.T) {
+	server := &fakeServer{}
+	node := newFakeNode(t, server, WithNodeOnStop(func() error {
+		return fmt.Errorf("boom")
+	}))
+
+	require.NoError(t, node.Start())
+	assert.EqualError(t, node.CloseWithTimeout(time.Second), "stop hook failed: boom")
+
+	assert.Equal(t, []string{"dial", "auto-recovery true", "start", "stop", "close"}, server.calls)
+}
+
+// The data directory must exist by default.
+func TestNew_MissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	_, err := New(1, "@1", dir)
+	assert.EqualError(t, err, fmt.Sprintf("data directory %q does not exist", dir))
+}
+
+func TestCheckDir_Create(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	require.NoError(t, checkDir(dir, true, 0700))
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	// No stray files are left behind by the writability check.
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 0)
+}
+
+func TestCheckDir_NotADirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+
+	err := checkDir(path, true, 0700)
+	assert.EqualError(t, err, fmt.Sprintf("data directory %q is not a directory", path))
+}
+
+func TestCheckDir_NotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.Chmod(dir, 0500))
+	defer os.Chmod(dir, 0700)
+
+	err := checkDir(dir, false, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not writable")
+}
+
+func TestNode_Preflight(t *testing.T) {
+	node := newFakeNode(t, &fakeServer{})
+
+	results, err := node.Preflight(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for i, name := range []string{"data-dir", "disk-space", "bind-address"} {
+		assert.Equal(t, name, results[i].Name)
+		assert.True(t, results[i].OK, results[i].Detail)
+	}
+}
+
+func TestNode_PreflightNotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	node := newFakeNode(t, &fakeServer{})
+	require.NoError(t, os.Chmod(node.dir, 0500))
+	defer os.Chmod(node.dir, 0700)
+
+	results, err := node.Preflight(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "data-dir", results[0].Name)
+	assert.False(t, results[0].OK)
+	assert.Contains(t, results[0].Detail, "is not writable")
+	assert.True(t, results[2].OK)
+}
+
+func TestNode_PreflightAddressInUse(t *testing.T) {
+	listener, err := net.Listen("unix", "@1")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	node := newFakeNode(t, &fakeServer{})
+
+	results, err := node.Preflight(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "bind-address", results[2].Name)
+	assert.False(t, results[2].OK)
+	assert.Contains(t, results[2].Detail, "address already in use")
+	assert.True(t, results[0].OK)
+}
+
+// Return the options resulting from applying the given ones to the defaults.
+func newOptions(options ...Option) *options {
+	o := defaultOptions()
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// Create a Node backed by the given fake server.
+func newFakeNode(t *testing.T, server *fakeServer, options ...Option) *Node {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	node, err := newNode(ctx, cancel, server, 1, "@1", t.TempDir(), newOptions(options...))
+	require.NoError(t, err)
+	return node
+}
+
+// In-memory implementation of bindings.NodeInterface, recording the calls it
+// receives.
+type fakeServer struct {
+	calls    []string
+	address  string
+	bindErrs []error // Returned by successive SetBindAddress calls
+	startErr error
+	stopErr  error
+}
+
+func (f *fakeServer) record(format string, a ...interface{}) {
+	f.calls = append(f.calls, fmt.Sprintf(format, a...))
+}
+
+func (f *fakeServer) SetDialFunc(protocol.DialFunc) error {
+	f.record("dial")
+	return nil
+}
+
+func (f *fakeServer) SetBindAddress(address string) error {
+	f.record("bind %s", address)
+	if len(f.bindErrs) > 0 {
+		err := f.bindErrs[0]
+		f.bindErrs = f.bindErrs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	f.address = address
+	return nil
+}
+
+func (f *fakeServer) SetNetworkLatency(nanoseconds uint64) error {
+	f.record("latency %d", nanoseconds)
+	return nil
+}
+
+func (f *fakeServer) SetSnapshotParams(params bindings.SnapshotParams) error {
+	f.record("snapshot %d %d", params.Threshold, params.Trailing)
+	return nil
+}
+
+func (f *fakeServer) SetFailureDomain(code uint64) error {
+	f.record("failure domain %d", code)
+	return nil
+}
+
+func (f *fakeServer) EnableDiskMode() error {
+	f.record("disk mode")
+	return nil
+}
+
+func (f *fakeServer) SetAutoRecovery(on bool) error {
+	f.record("auto-recovery %v", on)
+	return nil
+}
+
+func (f *fakeServer) GetBindAddress() string {
+	return f.address
+}
+
+func (f *fakeServer) Start() error {
+	f.record("start")
+	return f.startErr
+}
+
+func (f *fakeServer) Stop() error {
+	f.record("stop")
+	return f.stopErr
+}
+
+func (f *fakeServer) Close() {
+	f.record("close")
+}
+
+func (f *fakeServer) Recover(cluster []protocol.NodeInfo) error {
+	f.record("recover %d", len(cluster))
+	return nil
+}
+
+// If stopping blocks, closeWithTimeout returns after the timeout and releases
+// resources once stopping completes.
+func TestCloseWithTimeout_StopBlocks(t *testing.T) {
+	unblock := make(chan struct{})
+	released := make(chan struct{})
+
+	stopped := int32(0)
+	stop := func() error {
+		<-unblock
+		atomic.StoreInt32(&stopped, 1)
+		return nil
+	}
+	release := func() { close(released) }
+
+	err := closeWithTimeout(stop, release, 50*time.Millisecond)
+	assert.EqualError(t, err, "server did not stop within 50ms")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&stopped))
+
+	select {
+	case <-released:
+		t.Fatal("resources released while stop is still running")
+	default:
+	}
+
+	close(unblock)
+	<-released
+}
+
+func TestCloseWithTimeout_StopFails(t *testing.T) {
+	stop := func() error { return fmt.Errorf("boom") }
+	release := func() { t.Fatal("resources released after stop failure") }
+
+	err := closeWithTimeout(stop, release, time.Second)
+	assert.EqualError(t, err, "server failed to stop: boom")
+}
+
+func TestCloseWithTimeout_Success(t *testing.T) {
+	released := false
+	stop := func() error { return nil }
+	release := func() { released = true }
+
+	assert.NoError(t, closeWithTimeout(stop, release, time.Second))
+	assert.True(t, released)
+}
@@ -0,0 +1,584 @@
+package dqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dqlite "github.com/canonical/go-dqlite"
+	"github.com/canonical/go-dqlite/client"
+	"github.com/canonical/go-dqlite/driver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNode_PromoteDemote(t *testing.T) {
+	node1, cleanup := newNode(t, 1)
+	defer cleanup()
+
+	node2, cleanup := newNode(t, 2)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := client.NewInmemNodeStore()
+	require.NoError(t, store.Set(ctx, []client.NodeInfo{{ID: 1, Address: node1.BindAddress()}}))
+
+	cli, err := client.FindLeader(ctx, store)
+	require.NoError(t, err)
+	defer cli.Close()
+
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 2, Address: node2.BindAddress()}))
+	assert.Equal(t, client.Spare, nodeRole(t, cli, 2))
+
+	require.NoError(t, node2.Promote(ctx, store, nil))
+	assert.Equal(t, client.Voter, nodeRole(t, cli, 2))
+
+	// Promoting again is a no-op.
+	require.NoError(t, node2.Promote(ctx, store, nil))
+	assert.Equal(t, client.Voter, nodeRole(t, cli, 2))
+
+	require.NoError(t, node2.Demote(ctx, store, nil))
+	assert.Equal(t, client.StandBy, nodeRole(t, cli, 2))
+
+	// Demoting again is a no-op.
+	require.NoError(t, node2.Demote(ctx, store, nil))
+	assert.Equal(t, client.StandBy, nodeRole(t, cli, 2))
+}
+
+func TestNode_TransferTo(t *testing.T) {
+	node1, cleanup := newNode(t, 1)
+	defer cleanup()
+
+	node2, cleanup := newNode(t, 2)
+	defer cleanup()
+
+	node3, cleanup := newNode(t, 3)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node1.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 2, Address: node2.BindAddress(), Role: client.Voter}))
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 3, Address: node3.BindAddress(), Role: client.Spare}))
+
+	// The target must be a voter.
+	err = node1.TransferTo(ctx, 3)
+	assert.EqualError(t, err, "node 3 is not an eligible voter")
+
+	// Only the leader can transfer leadership.
+	err = node2.TransferTo(ctx, 1)
+	assert.EqualError(t, err, "node 2 is not the leader")
+
+	require.NoError(t, node1.TransferTo(ctx, 2))
+
+	cli2, err := client.New(ctx, node2.BindAddress())
+	require.NoError(t, err)
+	defer cli2.Close()
+
+	leader, err := cli2.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), leader.ID)
+}
+
+func TestNode_StepDown(t *testing.T) {
+	node1, cleanup := newNode(t, 1)
+	defer cleanup()
+
+	node2, cleanup := newNode(t, 2)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node1.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	// There's no other voter yet.
+	err = node1.StepDown(ctx)
+	assert.EqualError(t, err, "no other voter to hand leadership to")
+
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 2, Address: node2.BindAddress(), Role: client.Voter}))
+
+	// Only the leader can step down.
+	err = node2.StepDown(ctx)
+	assert.EqualError(t, err, "node 2 is not the leader")
+
+	require.NoError(t, node1.StepDown(ctx))
+
+	cli2, err := client.New(ctx, node2.BindAddress())
+	require.NoError(t, err)
+	defer cli2.Close()
+
+	leader, err := cli2.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), leader.ID)
+}
+
+func TestNode_LastSnapshot(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	threshold := uint64(8)
+	address := "@2001"
+	node, err := dqlite.New(
+		1, address, dir,
+		dqlite.WithBindAddress(address),
+		dqlite.WithSnapshotParams(dqlite.SnapshotParams{Threshold: threshold, Trailing: threshold}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, node.Start())
+	defer node.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// No snapshot yet.
+	snapshot, err := node.LastSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.SnapshotInfo{}, *snapshot)
+
+	// Each write appends one entry to the raft log, on top of the initial
+	// configuration entry.
+	store := client.NewInmemNodeStore()
+	require.NoError(t, store.Set(ctx, []client.NodeInfo{{ID: 1, Address: address}}))
+
+	drv, err := driver.New(store)
+	require.NoError(t, err)
+	sql.Register("dqlite-node-test-snapshot", drv)
+
+	db, err := sql.Open("dqlite-node-test-snapshot", "test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	writes := 2 * int(threshold)
+	_, err = db.ExecContext(ctx, "CREATE TABLE foo (n INT)")
+	require.NoError(t, err)
+	for i := 1; i < writes; i++ {
+		_, err = db.ExecContext(ctx, "INSERT INTO foo(n) VALUES(?)", i)
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		snapshot, err = node.LastSnapshot(ctx)
+		require.NoError(t, err)
+		return snapshot.Index != 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// A snapshot is taken once the number of applied entries reaches the
+	// threshold, so its index can't be lower than that, nor higher than the
+	// index of the last entry.
+	assert.Equal(t, uint64(1), snapshot.Term)
+	assert.GreaterOrEqual(t, snapshot.Index, threshold)
+	assert.LessOrEqual(t, snapshot.Index, uint64(1+writes))
+	assert.WithinDuration(t, time.Now(), snapshot.Timestamp, time.Minute)
+
+	// A canceled context is honored.
+	cancel()
+	_, err = node.LastSnapshot(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestNode_Health(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	address := "@2001"
+	node, err := dqlite.New(1, address, dir, dqlite.WithBindAddress(address))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report, err := node.Health(ctx, dqlite.HealthOptions{})
+	require.NoError(t, err)
+	assert.False(t, report.OK)
+	assert.False(t, report.Started)
+	assert.Equal(t, "node not started", report.Reason)
+
+	require.NoError(t, node.Start())
+	defer node.Close()
+
+	report, err = node.Health(ctx, dqlite.HealthOptions{})
+	require.NoError(t, err)
+	assert.True(t, report.OK)
+	assert.True(t, report.Started)
+	assert.True(t, report.LeaderReachable)
+	assert.Equal(t, uint64(1), report.Leader.ID)
+
+	// A done context makes the checks fail altogether.
+	cancel()
+	_, err = node.Health(ctx, dqlite.HealthOptions{})
+	assert.Equal(t, context.Canceled, err)
+}
+
+// If the abstract socket is still in use by someone else, binding is retried.
+// A follower whose leader goes away reports itself as unhealthy.
+func TestNode_HealthLeaderGone(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	node1, err := dqlite.New(1, "@2001", dir, dqlite.WithBindAddress("@2001"))
+	require.NoError(t, err)
+	require.NoError(t, node1.Start())
+
+	node2, cleanup := newNode(t, 2)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node1.BindAddress())
+	require.NoError(t, err)
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 2, Address: node2.BindAddress()}))
+	require.NoError(t, cli.Assign(ctx, 2, client.Voter))
+	cli.Close()
+
+	assert.Eventually(t, func() bool {
+		report, err := node2.Health(ctx, dqlite.HealthOptions{})
+		return err == nil && report.OK
+	}, 5*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, node1.Close())
+
+	report, err := node2.Health(ctx, dqlite.HealthOptions{LeaderTimeout: time.Second})
+	require.NoError(t, err)
+	assert.False(t, report.OK)
+	assert.True(t, report.Started)
+	assert.False(t, report.LeaderReachable)
+	assert.Regexp(t, "^(leader unreachable|no known leader)", report.Reason)
+}
+
+func TestNode_BindRetry(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	address := "@2001"
+	listener, err := net.Listen("unix", address)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		listener.Close()
+	}()
+
+	node, err := dqlite.New(
+		1, address, dir,
+		dqlite.WithBindAddress(address),
+		dqlite.WithNodeBindRetry(5, 50*time.Millisecond),
+	)
+	require.NoError(t, err)
+	require.NoError(t, node.Start())
+	require.NoError(t, node.Close())
+}
+
+// Without retries, binding fails if the abstract socket is in use.
+func TestNode_BindNoRetry(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	address := "@2001"
+	listener, err := net.Listen("unix", address)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	_, err = dqlite.New(1, address, dir, dqlite.WithBindAddress(address))
+	assert.Error(t, err)
+}
+
+func TestReadClusterYAML(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	data := []byte(`- ID: 1
+  Address: 10.0.0.1:9001
+  Role: 0
+- ID: 2
+  Address: 10.0.0.2:9001
+  Role: 1
+- ID: 3
+  Address: 10.0.0.3:9001
+  Role: 2
+`)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cluster.yaml"), data, 0600))
+
+	nodes, err := dqlite.ReadClusterYAML(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []dqlite.NodeInfo{
+		{ID: 1, Address: "10.0.0.1:9001", Role: client.Voter},
+		{ID: 2, Address: "10.0.0.2:9001", Role: client.StandBy},
+		{ID: 3, Address: "10.0.0.3:9001", Role: client.Spare},
+	}, nodes)
+}
+
+func TestReadClusterYAML_Missing(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	nodes, err := dqlite.ReadClusterYAML(dir)
+	require.NoError(t, err)
+	assert.Empty(t, nodes)
+}
+
+func TestWriteClusterYAML(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	nodes := []dqlite.NodeInfo{
+		{ID: 1, Address: "@1", Role: client.Voter},
+		{ID: 2, Address: "@2", Role: client.Spare},
+	}
+	require.NoError(t, dqlite.WriteClusterYAML(dir, nodes))
+
+	read, err := dqlite.ReadClusterYAML(dir)
+	require.NoError(t, err)
+	assert.Equal(t, nodes, read)
+}
+
+// Management calls can be made over an extra Unix listener while cluster
+// traffic flows over the TCP bind address.
+func TestNode_ExtraListener(t *testing.T) {
+	dir1, cleanup := newDir(t)
+	defer cleanup()
+
+	listener, err := net.Listen("unix", "@dqlite-test-extra")
+	require.NoError(t, err)
+
+	node1, err := dqlite.New(
+		1, "127.0.0.1:9071", dir1,
+		dqlite.WithBindAddress("127.0.0.1:9071"),
+		dqlite.WithNodeExtraListener(listener),
+	)
+	require.NoError(t, err)
+	require.NoError(t, node1.Start())
+	defer node1.Close()
+
+	dir2, cleanup := newDir(t)
+	defer cleanup()
+
+	node2, err := dqlite.New(2, "127.0.0.1:9072", dir2, dqlite.WithBindAddress("127.0.0.1:9072"))
+	require.NoError(t, err)
+	require.NoError(t, node2.Start())
+	defer node2.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, "@dqlite-test-extra")
+	require.NoError(t, err)
+	defer cli.Close()
+
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 2, Address: "127.0.0.1:9072"}))
+	require.NoError(t, cli.Assign(ctx, 2, client.Voter))
+
+	leader, err := cli.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), leader.ID)
+	assert.Equal(t, "127.0.0.1:9071", leader.Address)
+
+	// The TCP bind address serves the same requests.
+	tcp, err := client.New(ctx, "127.0.0.1:9071")
+	require.NoError(t, err)
+	defer tcp.Close()
+
+	nodes, err := tcp.Cluster(ctx)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, client.Voter, nodeRole(t, cli, 2))
+}
+
+func TestNode_Info(t *testing.T) {
+	node1, cleanup := newNode(t, 1)
+	defer cleanup()
+
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	node2, err := dqlite.New(2, "@2002", dir, dqlite.WithBindAddress("@2002"), dqlite.WithFailureDomain(3))
+	require.NoError(t, err)
+	require.NoError(t, node2.Start())
+	defer node2.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := node1.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.NodeInfo{ID: 1, Address: "@2001", Role: client.Voter}, info.NodeInfo)
+
+	store := client.NewInmemNodeStore()
+	require.NoError(t, store.Set(ctx, []client.NodeInfo{{ID: 1, Address: node1.BindAddress()}}))
+
+	cli, err := client.FindLeader(ctx, store)
+	require.NoError(t, err)
+	defer cli.Close()
+
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 2, Address: "@2002"}))
+	require.NoError(t, cli.Assign(ctx, 2, client.Voter))
+
+	assert.Eventually(t, func() bool {
+		info, err := node2.Info(ctx)
+		return err == nil && info.Role == client.Voter
+	}, 2*time.Second, 50*time.Millisecond)
+
+	info, err = node2.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), info.ID)
+	assert.Equal(t, "@2002", info.Address)
+	assert.Equal(t, uint64(3), info.FailureDomain)
+	assert.Equal(t, uint64(0), info.Weight)
+}
+
+// A node with a leadership priority reacquires leadership after losing it.
+func TestNode_LeadershipPriority(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	node1, err := dqlite.New(1, "@2001", dir, dqlite.WithBindAddress("@2001"), dqlite.WithNodeLeadershipPriority(10))
+	require.NoError(t, err)
+	require.NoError(t, node1.Start())
+	defer node1.Close()
+
+	node2, cleanup := newNode(t, 2)
+	defer cleanup()
+
+	node3, cleanup := newNode(t, 3)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node1.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 2, Address: node2.BindAddress()}))
+	require.NoError(t, cli.Assign(ctx, 2, client.Voter))
+	require.NoError(t, cli.Add(ctx, client.NodeInfo{ID: 3, Address: node3.BindAddress()}))
+	require.NoError(t, cli.Assign(ctx, 3, client.Voter))
+
+	require.NoError(t, node1.TransferTo(ctx, 2))
+
+	leaderID := func() uint64 {
+		cli, err := client.New(ctx, node2.BindAddress())
+		if err != nil {
+			return 0
+		}
+		defer cli.Close()
+		leader, err := cli.Leader(ctx)
+		if err != nil {
+			return 0
+		}
+		return leader.ID
+	}
+
+	assert.Eventually(t, func() bool { return leaderID() == 1 }, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestNode_ClusterStream(t *testing.T) {
+	node, cleanup := newNode(t, 1)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	nodes := []client.NodeInfo{}
+	err := node.ClusterStream(ctx, func(info client.NodeInfo) error {
+		nodes = append(nodes, info)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []client.NodeInfo{{ID: 1, Address: "@2001", Role: client.Voter}}, nodes)
+}
+
+func TestNode_SQLiteConfig(t *testing.T) {
+	node, cleanup := newNode(t, 1)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	require.NoError(t, cli.BulkLoad(ctx, "test.db", strings.NewReader("CREATE TABLE test (n INT)")))
+
+	config, err := node.SQLiteConfig(ctx, "test.db")
+	require.NoError(t, err)
+
+	// These are the settings dqlite applies to its leader connections.
+	assert.Equal(t, int64(4096), config.PageSize)
+	assert.Equal(t, "wal", config.JournalMode)
+	assert.Equal(t, int64(0), config.Synchronous)
+	assert.NotZero(t, config.CacheSize)
+}
+
+// Return the role of the node with the given ID.
+func nodeRole(t *testing.T, cli *client.Client, id uint64) client.NodeRole {
+	t.Helper()
+
+	nodes, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+
+	for _, node := range nodes {
+		if node.ID == id {
+			return node.Role
+		}
+	}
+
+	t.Fatalf("node %d not found", id)
+	return -1
+}
+
+// Create and start a new node with the given ID.
+func newNode(t *testing.T, id uint64) (*dqlite.Node, func()) {
+	t.Helper()
+	dir, dirCleanup := newDir(t)
+
+	address := fmt.Sprintf("@%d", id+2000)
+	node, err := dqlite.New(id, address, dir, dqlite.WithBindAddress(address))
+	require.NoError(t, err)
+
+	err = node.Start()
+	require.NoError(t, err)
+
+	cleanup := func() {
+		require.NoError(t, node.Close())
+		dirCleanup()
+	}
+
+	return node, cleanup
+}
+
+// Return a new temporary directory.
+func newDir(t *testing.T) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "dqlite-node-test-")
+	assert.NoError(t, err)
+
+	cleanup := func() {
+		_, err := os.Stat(dir)
+		if err != nil {
+			assert.True(t, os.IsNotExist(err))
+		} else {
+			assert.NoError(t, os.RemoveAll(dir))
+		}
+	}
+
+	return dir, cleanup
+}
@@ -0,0 +1,89 @@
+package dqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+)
+
+// How often a node with a leadership priority checks the current leader.
+const leadershipCheckInterval = time.Second
+
+// Periodically reclaim leadership from leaders with a lower priority, until
+// the node is closed.
+func (s *Node) reclaimLeadershipLoop(address string) {
+	defer s.reclaiming.Done()
+
+	registered := false
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(leadershipCheckInterval):
+		}
+
+		ctx, cancel := context.WithTimeout(s.ctx, leadershipCheckInterval)
+		// Errors are transient from our point of view, just try again
+		// at the next round.
+		s.reclaimLeadership(ctx, address, &registered)
+		cancel()
+	}
+}
+
+// Record our priority if not done yet and ask the current leader to transfer
+// leadership to us if its priority is lower.
+func (s *Node) reclaimLeadership(ctx context.Context, address string, registered *bool) error {
+	cli, err := client.New(ctx, address, client.WithDialFunc(s.dial))
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return err
+	}
+	if leader.ID == 0 {
+		return nil
+	}
+
+	if leader.ID != s.id {
+		cli, err = client.New(ctx, leader.Address, client.WithDialFunc(s.dial))
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+	}
+
+	if !*registered {
+		if err := cli.SetLeadershipPriority(ctx, s.id, s.priority); err != nil {
+			return err
+		}
+		*registered = true
+	}
+
+	if leader.ID == s.id {
+		return nil
+	}
+
+	priority, err := cli.LeadershipPriority(ctx, leader.ID)
+	if err != nil {
+		return err
+	}
+	if priority >= s.priority {
+		return nil
+	}
+
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node.ID == s.id && node.Role == client.Voter {
+			return cli.Transfer(ctx, s.id)
+		}
+	}
+
+	return nil
+}